@@ -0,0 +1,10 @@
+# Check the API every time Terraform plans, without storing the check in state
+data "checkmate_http_health" "api" {
+  url                   = "https://api.example.com/healthz"
+  status_code           = "200"
+  consecutive_successes = 2
+}
+
+output "api_healthy" {
+  value = data.checkmate_http_health.api.passed
+}
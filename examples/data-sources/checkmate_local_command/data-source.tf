@@ -0,0 +1,12 @@
+# Fail the plan if the cluster isn't reachable
+data "checkmate_local_command" "cluster" {
+  command = "kubectl version -o json"
+
+  output_format   = "json"
+  output_jsonpath = "{.serverVersion.major}"
+  output_value    = "^1$"
+}
+
+output "server_version" {
+  value = data.checkmate_local_command.cluster.output.serverVersion.gitVersion
+}
@@ -0,0 +1,13 @@
+data "checkmate_tcp_echo" "example" {
+  host             = "echo.example.com"
+  port             = 7
+  message          = "PING"
+  expected_message = "PING"
+
+  # Don't fail the plan if the server is down, just set passed to false
+  create_anyway_on_check_failure = true
+}
+
+output "echo_passed" {
+  value = data.checkmate_tcp_echo.example.passed
+}
@@ -72,3 +72,15 @@ resource "checkmate_http_health" "example_json_path_regex" {
   jsonpath              = "{ .User-Agent }"
   json_value            = "curl/.*"
 }
+
+resource "checkmate_http_health" "example_compression" {
+  url                       = "https://httpbin.org/brotli"
+  request_timeout           = 1000
+  method                    = "GET"
+  interval                  = 1
+  status_code               = 200
+  consecutive_successes     = 2
+  expected_content_encoding = "br"
+  jsonpath                  = "{ .brotli }"
+  json_value                = "true"
+}
@@ -14,21 +14,143 @@ resource "checkmate_local_command" "example" {
   # We want 2 successes in a row
   consecutive_successes = 2
 
-  # Create the script file before running the attempts
+  # Create the script file before running the attempts. It's deleted when the
+  # resource is destroyed
   create_file = {
     name             = "fancy_script.py"
     contents         = "print('hello world')"
     use_working_dir  = true
     create_directory = true
+    exact_name       = true
+    mode             = "0755"
   }
 
   create_anyway_on_check_failure = false
 }
 
+# Pass only when the command prints the expected output, without wrapping it
+# in grep
+resource "checkmate_local_command" "example_output" {
+  command = "kubectl get deployment web -o name"
+
+  # kubectl returns 1 while the deployment doesn't exist yet
+  expected_exit_codes = "0"
+  stdout_regex        = "^deployment.apps/web"
+  fail_on_stderr      = true
+}
+
+# Parse JSON output and assert on it with JSONPath. The parsed value is
+# available in `output`
+resource "checkmate_local_command" "example_json" {
+  command = "kubectl get deployment web -o json"
+
+  output_format   = "json"
+  output_jsonpath = "{.status.readyReplicas}"
+  output_value    = "^[1-9][0-9]*$"
+}
+
+# Use bash with strict mode instead of sh
+resource "checkmate_local_command" "example_bash" {
+  interpreter = ["bash", "-euo", "pipefail", "-c"]
+  command     = "curl -sf http://localhost:8080/healthz | grep -q ok"
+}
+
+# Don't leak the credentials of the provider to the command. Only PATH, HOME,
+# KUBECONFIG and the variables in env are set
+resource "checkmate_local_command" "example_isolated" {
+  command       = "kubectl get --raw /readyz"
+  inherit_env   = false
+  env_allowlist = ["KUBECONFIG"]
+}
+
+# Run a program directly, without a shell, so arguments don't need quoting
+resource "checkmate_local_command" "example_args" {
+  args = ["pg_isready", "--host", "db.example.com", "--port", "5432"]
+}
+
+variable "kubeconfig" {
+  type = string
+}
+
+variable "ca_cert_base64" {
+  type = string
+}
+
+# Create several files for the command in a temporary directory
+resource "checkmate_local_command" "example_files" {
+  command = "sh $CHECKMATE_FILE_SCRIPT"
+
+  files = {
+    kubeconfig = {
+      contents = var.kubeconfig
+    }
+    ca = {
+      path            = "certs/ca.pem"
+      contents_base64 = var.ca_cert_base64
+    }
+    script = {
+      path     = "check.sh"
+      contents = "kubectl --kubeconfig $CHECKMATE_FILE_KUBECONFIG --certificate-authority $CHECKMATE_DIR/certs/ca.pem get --raw /readyz"
+      mode     = "0755"
+    }
+  }
+}
+
+# Wait for the namespace to be deleted when the resource is destroyed
+resource "checkmate_local_command" "example_destroy" {
+  command = "kubectl get namespace my-app"
+
+  destroy = {
+    command  = "! kubectl get namespace my-app"
+    timeout  = 300000
+    interval = 5000
+  }
+}
+
+# Pipe data into the command
+resource "checkmate_local_command" "example_stdin" {
+  command = "kubectl apply --dry-run=server -f -"
+  stdin   = file("${path.module}/manifest.yaml")
+}
+
+# Stream the output of a long running command to the Terraform logs while it
+# runs, and only keep the end of it in the state
+resource "checkmate_local_command" "example_helm_test" {
+  command          = "helm test my-release --logs"
+  timeout          = 600000
+  command_timeout  = 600000
+  output_log_level = "info"
+  max_output_bytes = 4096
+}
+
+variable "db_password" {
+  type      = string
+  sensitive = true
+}
+
+# Keep credentials out of the logs and the state. The value of PGPASSWORD and
+# anything that looks like a bearer token is replaced with [REDACTED]
+resource "checkmate_local_command" "example_secrets" {
+  command = "psql -h db.example.com -U app -c 'select 1'"
+
+  secret_env = {
+    PGPASSWORD = var.db_password
+  }
+  redact_patterns = ["Bearer [A-Za-z0-9._-]+"]
+}
+
 output "stdout" {
   value = checkmate_local_command.example.stdout
 }
 
 output "stderr" {
   value = checkmate_local_command.example.stderr
-}
\ No newline at end of file
+}
+output "last_error" {
+  # Why the last attempt failed, for example "exit 1" or "killed by timeout"
+  value = checkmate_local_command.example_output.last_error
+}
+
+output "ready_replicas" {
+  value = checkmate_local_command.example_json.output.status.readyReplicas
+}
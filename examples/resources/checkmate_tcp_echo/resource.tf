@@ -46,3 +46,19 @@ resource "checkmate_tcp_echo" "example" {
   # Set a number of consecutive sucesses to make the check pass
   consecutive_successes = 5
 }
+
+# Binary protocols can be checked by providing the payloads as hex or base64.
+# This sends a Redis PING command and expects a +PONG reply.
+resource "checkmate_tcp_echo" "example_binary" {
+  host = "redis.local"
+  port = 6379
+
+  # "*1\r\n$4\r\nPING\r\n"
+  message_hex = "2a310d0a 24340d0a 50494e47 0d0a"
+
+  # Don't append a newline, the payload is already complete
+  append_newline = false
+
+  # "+PONG"
+  expected_base64 = "K1BPTkc="
+}
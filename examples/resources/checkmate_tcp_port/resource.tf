@@ -0,0 +1,47 @@
+resource "checkmate_tcp_port" "example" {
+  # The hostname to connect to
+  host = "db.example.com"
+
+  # The TCP port that should be accepting connections
+  port = 5432
+
+  # Set a number of consecutive sucesses to make the check pass
+  consecutive_successes = 3
+}
+
+# Optionally, check the banner the server sends after connecting
+resource "checkmate_tcp_port" "example_banner" {
+  host         = "git.example.com"
+  port         = 22
+  banner_regex = "^SSH-2\\.0-"
+}
+
+# Assert that a port is not reachable, for example because a firewall rule
+# should be blocking it
+resource "checkmate_tcp_port" "example_closed" {
+  host               = "internal.example.com"
+  port               = 6379
+  expect_closed      = true
+  connection_timeout = 1000
+}
+
+# Check every address behind a DNS name, passing if at least 2 of them accept
+# connections
+resource "checkmate_tcp_port" "example_all_addresses" {
+  host          = "lb.example.com"
+  port          = 443
+  all_addresses = true
+  quorum        = "2"
+}
+
+# Check the port again on every refresh, so `terraform plan` shows the resource
+# will be replaced if the database stopped accepting connections
+resource "checkmate_tcp_port" "example_recheck" {
+  host               = "db.example.com"
+  port               = 5432
+  recheck_on_refresh = "attempt"
+}
+
+output "remote_address" {
+  value = checkmate_tcp_port.example.remote_address
+}
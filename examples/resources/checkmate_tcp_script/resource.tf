@@ -0,0 +1,33 @@
+resource "checkmate_tcp_script" "example" {
+  # The hostname to connect to
+  host = "mail.example.com"
+
+  # The TCP port to connect to
+  port = 25
+
+  # The conversation to run on every attempt
+  steps = [
+    {
+      # Wait for the banner
+      expect = "^220 (?P<server>\\S+)"
+    },
+    {
+      send   = "EHLO checkmate.local\r\n"
+      expect = "250 .*\r\n"
+    },
+    {
+      send   = "QUIT\r\n"
+      expect = "221"
+    },
+  ]
+
+  # The whole conversation must complete within 3 seconds
+  single_attempt_timeout = 3000
+
+  # Set a number of consecutive sucesses to make the check pass
+  consecutive_successes = 2
+}
+
+output "smtp_server" {
+  value = checkmate_tcp_script.example.captures["server"]
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding is sent when decompression is enabled and the user did not
+// provide their own Accept-Encoding header.
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// parseContentEncoding splits a Content-Encoding header value into its
+// codings, in the order they were applied by the server. The identity coding
+// is dropped.
+func parseContentEncoding(header string) []string {
+	var codings []string
+	for _, c := range strings.Split(header, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" || c == "identity" {
+			continue
+		}
+		codings = append(codings, c)
+	}
+	return codings
+}
+
+// decodeBody wraps r so that reading from it yields the body with all the
+// given content codings removed. The returned function releases the decoders
+// and must be called once the body has been read.
+func decodeBody(r io.Reader, codings []string) (io.Reader, func(), error) {
+	var closers []io.Closer
+	release := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+	var err error
+	// codings are listed in the order they were applied, so undo them in reverse
+	for i := len(codings) - 1; i >= 0; i-- {
+		switch codings[i] {
+		case "gzip", "x-gzip":
+			var gz *gzip.Reader
+			gz, err = gzip.NewReader(r)
+			if err != nil {
+				release()
+				return nil, nil, fmt.Errorf("gzip: %w", err)
+			}
+			closers = append(closers, gz)
+			r = gz
+		case "deflate":
+			var fl io.ReadCloser
+			fl, err = newDeflateReader(r)
+			if err != nil {
+				release()
+				return nil, nil, fmt.Errorf("deflate: %w", err)
+			}
+			closers = append(closers, fl)
+			r = fl
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			var zr *zstd.Decoder
+			zr, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				release()
+				return nil, nil, fmt.Errorf("zstd: %w", err)
+			}
+			closers = append(closers, zr.IOReadCloser())
+			r = zr
+		default:
+			release()
+			return nil, nil, fmt.Errorf("unsupported content encoding %q", codings[i])
+		}
+	}
+	return r, release, nil
+}
+
+// newDeflateReader handles both the zlib wrapped format mandated by the HTTP
+// spec and the raw deflate streams some servers send instead.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// checkContentEncoding reports whether the last coding applied by the server
+// is one of the comma separated encodings in expected. An uncompressed
+// response matches "identity".
+func checkContentEncoding(expected string, codings []string) bool {
+	last := "identity"
+	if len(codings) > 0 {
+		last = codings[len(codings)-1]
+	}
+	for _, e := range strings.Split(expected, ",") {
+		if strings.ToLower(strings.TrimSpace(e)) == last {
+			return true
+		}
+	}
+	return false
+}
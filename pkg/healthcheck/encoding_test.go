@@ -0,0 +1,151 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compress(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, err = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		w, err = zstd.NewWriter(&buf)
+	default:
+		t.Fatalf("unknown encoding %q", encoding)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHealthCheckDecompress(t *testing.T) {
+	body := []byte(`{"SomeField": "someValue"}`)
+	tests := []struct {
+		name             string
+		encoding         string
+		header           string
+		decompress       bool
+		expectedEncoding string
+		wantBody         string
+		wantEncoding     string
+		wantErr          bool
+	}{
+		{name: "gzip", encoding: "gzip", header: "gzip", decompress: true, wantBody: string(body), wantEncoding: "gzip"},
+		{name: "deflate", encoding: "deflate", header: "deflate", decompress: true, wantBody: string(body), wantEncoding: "deflate"},
+		{name: "raw deflate", encoding: "raw-deflate", header: "deflate", decompress: true, wantBody: string(body), wantEncoding: "deflate"},
+		{name: "brotli", encoding: "br", header: "br", decompress: true, wantBody: string(body), wantEncoding: "br"},
+		{name: "zstd", encoding: "zstd", header: "zstd", decompress: true, wantBody: string(body), wantEncoding: "zstd"},
+		{name: "uncompressed", decompress: true, wantBody: string(body)},
+		{name: "expected encoding matches", encoding: "br", header: "br", decompress: true, expectedEncoding: "gzip, br", wantBody: string(body), wantEncoding: "br"},
+		{name: "expected encoding does not match", decompress: true, expectedEncoding: "gzip", wantEncoding: "", wantErr: true},
+		{name: "expected identity", decompress: true, expectedEncoding: "identity", wantBody: string(body)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := body
+			if tt.encoding != "" {
+				payload = compress(t, tt.encoding, body)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.decompress && r.Header.Get("Accept-Encoding") != acceptEncoding {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if tt.header != "" {
+					w.Header().Set("Content-Encoding", tt.header)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(payload)
+			}))
+			defer server.Close()
+
+			args := &HttpHealthArgs{
+				URL:                     server.URL,
+				Method:                  "GET",
+				Timeout:                 500,
+				RequestTimeout:          200,
+				Interval:                50,
+				ConsecutiveSuccesses:    1,
+				StatusCode:              "200",
+				Decompress:              tt.decompress,
+				ExpectedContentEncoding: tt.expectedEncoding,
+				JSONPath:                "{.SomeField}",
+				JSONValue:               "someValue",
+			}
+			err := HealthCheck(context.Background(), args, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HealthCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if args.ResultBody != tt.wantBody {
+				t.Errorf("ResultBody = %q, want %q", args.ResultBody, tt.wantBody)
+			}
+			if args.ResultContentEncoding != tt.wantEncoding {
+				t.Errorf("ResultContentEncoding = %q, want %q", args.ResultContentEncoding, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestCheckContentEncoding(t *testing.T) {
+	tests := []struct {
+		expected string
+		codings  []string
+		want     bool
+	}{
+		{"gzip", []string{"gzip"}, true},
+		{"br, gzip", []string{"gzip"}, true},
+		{"BR", []string{"br"}, true},
+		{"gzip", []string{"br"}, false},
+		{"gzip", nil, false},
+		{"identity", nil, true},
+		{"gzip", []string{"br", "gzip"}, true},
+	}
+	for _, tt := range tests {
+		if got := checkContentEncoding(tt.expected, tt.codings); got != tt.want {
+			t.Errorf("checkContentEncoding(%q, %v) = %v, want %v", tt.expected, tt.codings, got, tt.want)
+		}
+	}
+}
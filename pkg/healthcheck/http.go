@@ -17,8 +17,11 @@ package healthcheck
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -29,6 +32,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
@@ -38,22 +42,34 @@ import (
 )
 
 type HttpHealthArgs struct {
-	URL                  string
-	Method               string
-	Timeout              int64
-	RequestTimeout       int64
-	Interval             int64
-	StatusCode           string
-	ConsecutiveSuccesses int64
-	Headers              map[string]string
-	IgnoreFailure        bool
-	Passed               bool
-	RequestBody          string
-	ResultBody           string
-	CABundle             string
-	InsecureTLS          bool
-	JSONPath             string
-	JSONValue            string
+	URL                     string
+	Method                  string
+	Timeout                 int64
+	RequestTimeout          int64
+	Interval                int64
+	StatusCode              string
+	ConsecutiveSuccesses    int64
+	MaxAttempts             int
+	Headers                 map[string]string
+	IgnoreFailure           bool
+	Passed                  bool
+	RequestBody             string
+	ResultBody              string
+	ResultBodyBase64        string
+	ResultBodySHA256        string
+	MaxBodyBytes            int64
+	Decompress              bool
+	ExpectedContentEncoding string
+	ResultContentEncoding   string
+	HTTPVersion             string
+	ResultHTTPVersion       string
+	CABundle                string
+	InsecureTLS             bool
+	JSONPath                string
+	JSONValue               string
+	AllAddresses            bool
+	Quorum                  string
+	AddressResults          map[string]bool
 }
 
 func HealthCheck(ctx context.Context, data *HttpHealthArgs, diag *diag.Diagnostics) error {
@@ -71,10 +87,25 @@ func HealthCheck(ctx context.Context, data *HttpHealthArgs, diag *diag.Diagnosti
 		return errors.New("both JSONPath and JSONValue must be specified")
 	}
 
+	var jsonPath *jsonpath.JSONPath
+	var jsonValue *regexp.Regexp
+	if data.JSONPath != "" {
+		jsonPath = jsonpath.New("parser")
+		if err := jsonPath.Parse(data.JSONPath); err != nil {
+			diagAddError(diag, "Invalid JSONPath expression", fmt.Sprintf("Could not parse JSONPath expression %q: %v", data.JSONPath, err))
+			return fmt.Errorf("parse JSONPath expression %q: %w", data.JSONPath, err)
+		}
+		jsonValue, err = regexp.Compile(data.JSONValue)
+		if err != nil {
+			diagAddError(diag, "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.JSONValue, err))
+			return fmt.Errorf("compile regex %q: %w", data.JSONValue, err)
+		}
+	}
+
 	var checkCode func(int) (bool, error)
-	// check the pattern once
-	_, err = checkStatusCode(data.StatusCode, 0, diag)
-	if err != nil {
+	// check the whole pattern once
+	if _, err := parseStatusCodePattern(data.StatusCode); err != nil {
+		diagAddError(diag, "Bad status code pattern", fmt.Sprintf("Invalid pattern %q: %v", data.StatusCode, err))
 		return fmt.Errorf("bad status code pattern: %w", err)
 	}
 	checkCode = func(c int) (bool, error) { return checkStatusCode(data.StatusCode, c, diag) }
@@ -86,14 +117,23 @@ func HealthCheck(ctx context.Context, data *HttpHealthArgs, diag *diag.Diagnosti
 			headers[k] = []string{v}
 		}
 	}
+	if data.Decompress && !hasHeader(headers, "Accept-Encoding") {
+		headers["Accept-Encoding"] = []string{acceptEncoding}
+	}
 
 	window := helpers.RetryWindow{
 		Context:              ctx,
 		Timeout:              time.Duration(data.Timeout) * time.Millisecond,
 		Interval:             time.Duration(data.Interval) * time.Millisecond,
 		ConsecutiveSuccesses: int(data.ConsecutiveSuccesses),
+		MaxAttempts:          data.MaxAttempts,
 	}
 	data.ResultBody = ""
+	data.ResultBodyBase64 = ""
+	data.ResultBodySHA256 = ""
+	data.ResultContentEncoding = ""
+	data.ResultHTTPVersion = ""
+	truncated := false
 
 	if data.CABundle != "" && data.InsecureTLS {
 		diagAddError(diag, "Conflicting configuration", "You cannot specify both custom CA and insecure TLS. Please use only one of them.")
@@ -109,12 +149,32 @@ func HealthCheck(ctx context.Context, data *HttpHealthArgs, diag *diag.Diagnosti
 	}
 	tlsConfig.InsecureSkipVerify = data.InsecureTLS
 
-	client := http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:   tlsConfig,
-			ForceAttemptHTTP2: true,
-		},
-		Timeout: time.Duration(data.RequestTimeout) * time.Millisecond,
+	quorum, err := helpers.ParseQuorum(data.Quorum)
+	if err != nil {
+		diagAddError(diag, "Invalid quorum", err.Error())
+		return err
+	}
+	data.AddressResults = map[string]bool{}
+
+	// clients by the address they dial, the empty address resolves the URL host
+	clients := map[string]*http.Client{}
+	newClient := func(addr string) (*http.Client, error) {
+		if client, ok := clients[addr]; ok {
+			return client, nil
+		}
+		transport, err := newTransport(data.HTTPVersion, tlsConfig, addr)
+		if err != nil {
+			return nil, err
+		}
+		clients[addr] = &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(data.RequestTimeout) * time.Millisecond,
+		}
+		return clients[addr], nil
+	}
+	if _, err := newClient(""); err != nil {
+		diagAddError(diag, "Client Error", err.Error())
+		return err
 	}
 
 	tflog.Debug(ctx, fmt.Sprintf("Starting HTTP health check. Overall timeout: %d ms, request timeout: %d ms", data.Timeout, data.RequestTimeout))
@@ -122,70 +182,61 @@ func HealthCheck(ctx context.Context, data *HttpHealthArgs, diag *diag.Diagnosti
 		tflog.Debug(ctx, fmt.Sprintf("%s: %s", h, v))
 	}
 
-	result := window.Do(func(attempt int, successes int) bool {
+	result := window.DoContext(func(attemptCtx context.Context, attempt int, successes int) bool {
 		if successes != 0 {
 			tflog.Trace(ctx, fmt.Sprintf("SUCCESS [%d/%d] http %s %s", successes, data.ConsecutiveSuccesses, data.Method, endpoint))
 		} else {
 			tflog.Trace(ctx, fmt.Sprintf("ATTEMPT #%d http %s %s", attempt, data.Method, endpoint))
 		}
 
-		httpResponse, err := client.Do(&http.Request{
-			URL:    endpoint,
-			Method: data.Method,
-			Header: headers,
-			Body:   io.NopCloser(strings.NewReader(data.RequestBody)),
-		})
-		if err != nil {
-			tflog.Warn(ctx, fmt.Sprintf("CONNECTION FAILURE %v", err))
-			return false
-		}
-
-		success, err := checkCode(httpResponse.StatusCode)
-		if err != nil {
-			diagAddError(diag, "check status code", err.Error())
-			multierror.Append(err, fmt.Errorf("check status code: %w", err))
-		}
-		if success {
-			tflog.Trace(ctx, fmt.Sprintf("SUCCESS CODE %d", httpResponse.StatusCode))
-			body, err := io.ReadAll(httpResponse.Body)
+		addrs := []string{""}
+		if data.AllAddresses {
+			resolved, err := helpers.LookupAddresses(attemptCtx, endpoint.Hostname())
 			if err != nil {
-				tflog.Warn(ctx, fmt.Sprintf("ERROR READING BODY %v", err))
-				data.ResultBody = ""
-			} else {
-				tflog.Warn(ctx, fmt.Sprintf("READ %d BYTES", len(body)))
-				data.ResultBody = string(body)
+				tflog.Warn(ctx, fmt.Sprintf("RESOLVE FAILURE %v", err))
+				return false
 			}
-		} else {
-			tflog.Trace(ctx, fmt.Sprintf("FAILURE CODE %d", httpResponse.StatusCode))
+			addrs = resolved
+		}
+		for _, addr := range addrs {
+			// the transport was validated before the first attempt
+			_, _ = newClient(addr)
 		}
 
-		// Check JSONPath
-		if data.JSONPath != "" && data.JSONValue != "" {
-			j := jsonpath.New("parser")
-			err = j.Parse(data.JSONPath)
-			if err != nil {
-				tflog.Warn(ctx, fmt.Sprintf("ERROR PARSING JSONPATH EXPRESSION %v", err))
-				return false
+		responses := helpers.FanOut(addrs, func(addr string) httpResult {
+			return fetch(attemptCtx, clients[addr], endpoint, headers, data)
+		})
+
+		data.AddressResults = map[string]bool{}
+		passedCount := 0
+		for i, res := range responses {
+			key := addrs[i]
+			if key == "" {
+				key = endpoint.Hostname()
 			}
-			var respJSON interface{}
-			err = json.Unmarshal([]byte(data.ResultBody), &respJSON)
-			if err != nil {
-				tflog.Warn(ctx, fmt.Sprintf("ERROR UNMARSHALLING JSON %v", err))
-				return false
+			passed, resTruncated := evaluate(ctx, data, res, checkCode, jsonPath, jsonValue, diag, i == 0)
+			if i == 0 {
+				truncated = resTruncated
 			}
-			buf := new(bytes.Buffer)
-			err = j.Execute(buf, respJSON)
-			if err != nil {
-				tflog.Warn(ctx, fmt.Sprintf("ERROR EXECUTING JSONPATH %v", err))
-				return false
+			data.AddressResults[key] = passed
+			if passed {
+				passedCount++
 			}
-			re := regexp.MustCompile(data.JSONValue)
-			return re.MatchString(buf.String())
 		}
 
-		return success
+		if !quorum.Satisfied(passedCount, len(responses)) {
+			if len(responses) > 1 {
+				tflog.Warn(ctx, fmt.Sprintf("%d/%d ADDRESSES PASSED, QUORUM IS %s", passedCount, len(responses), data.Quorum))
+			}
+			return false
+		}
+		return true
 	})
 
+	if truncated {
+		diagAddWarning(diag, "Response body truncated", fmt.Sprintf("The response body exceeded max_body_bytes and at most the first %d bytes were kept", data.MaxBodyBytes))
+	}
+
 	switch result {
 	case helpers.Success:
 		data.Passed = true
@@ -200,43 +251,259 @@ func HealthCheck(ctx context.Context, data *HttpHealthArgs, diag *diag.Diagnosti
 	return err
 }
 
+// httpResult is the response of a single address
+type httpResult struct {
+	response *http.Response
+	codings  []string
+	body     []byte
+	// sha256 is the hex encoded digest of the whole body, even if truncated
+	sha256 string
+	// truncated reports whether the body was longer than MaxBodyBytes
+	truncated bool
+	err       error
+	bodyErr   error
+}
+
+// fetch sends the request and reads the whole response body
+func fetch(ctx context.Context, client *http.Client, endpoint *url.URL, headers map[string][]string, data *HttpHealthArgs) httpResult {
+	var res httpResult
+	req := &http.Request{
+		URL:    endpoint,
+		Method: data.Method,
+		Header: headers,
+		Body:   io.NopCloser(strings.NewReader(data.RequestBody)),
+	}
+	res.response, res.err = client.Do(req.WithContext(ctx))
+	if res.err != nil {
+		return res
+	}
+	defer res.response.Body.Close()
+
+	res.codings = parseContentEncoding(strings.Join(res.response.Header.Values("Content-Encoding"), ","))
+	res.body, res.sha256, res.truncated, res.bodyErr = readResponseBody(res.response.Body, res.codings, data)
+	return res
+}
+
+// evaluate checks a single response, storing its results in data if record
+// is set. It returns whether the response passed and whether its body was
+// truncated.
+func evaluate(ctx context.Context, data *HttpHealthArgs, res httpResult, checkCode func(int) (bool, error), jsonPath *jsonpath.JSONPath, jsonValue *regexp.Regexp, diag *diag.Diagnostics, record bool) (bool, bool) {
+	if res.err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("CONNECTION FAILURE %v", res.err))
+		return false, false
+	}
+	httpResponse := res.response
+
+	if record {
+		data.ResultHTTPVersion = httpResponse.Proto
+	}
+	if !checkHTTPVersion(data.HTTPVersion, httpResponse) {
+		tflog.Warn(ctx, fmt.Sprintf("UNEXPECTED PROTOCOL %s", httpResponse.Proto))
+		return false, false
+	}
+
+	truncated := false
+	success, err := checkCode(httpResponse.StatusCode)
+	if err != nil {
+		diagAddError(diag, "check status code", err.Error())
+	}
+	if success {
+		tflog.Trace(ctx, fmt.Sprintf("SUCCESS CODE %d", httpResponse.StatusCode))
+		contentEncoding := strings.Join(res.codings, ", ")
+		if record {
+			data.ResultContentEncoding = contentEncoding
+		}
+		if data.ExpectedContentEncoding != "" && !checkContentEncoding(data.ExpectedContentEncoding, res.codings) {
+			tflog.Warn(ctx, fmt.Sprintf("UNEXPECTED CONTENT ENCODING %q", contentEncoding))
+			return false, false
+		}
+		if res.bodyErr != nil {
+			tflog.Warn(ctx, fmt.Sprintf("ERROR READING BODY %v", res.bodyErr))
+			if record {
+				data.ResultBody = ""
+				data.ResultBodyBase64 = ""
+				data.ResultBodySHA256 = ""
+			}
+		} else {
+			tflog.Warn(ctx, fmt.Sprintf("READ %d BYTES", len(res.body)))
+			if res.truncated {
+				tflog.Warn(ctx, fmt.Sprintf("BODY TRUNCATED TO %d BYTES", data.MaxBodyBytes))
+			}
+			if record {
+				setResultBody(data, res.body, res.sha256)
+			}
+			truncated = res.truncated
+		}
+	} else {
+		tflog.Trace(ctx, fmt.Sprintf("FAILURE CODE %d", httpResponse.StatusCode))
+	}
+
+	// Check JSONPath
+	if jsonPath != nil {
+		var body []byte
+		if success && res.bodyErr == nil {
+			body = res.body
+		}
+		var respJSON interface{}
+		err = json.Unmarshal(body, &respJSON)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("ERROR UNMARSHALLING JSON %v", err))
+			return false, truncated
+		}
+		buf := new(bytes.Buffer)
+		err = jsonPath.Execute(buf, respJSON)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("ERROR EXECUTING JSONPATH %v", err))
+			return false, truncated
+		}
+		return jsonValue.MatchString(buf.String()), truncated
+	}
+
+	return success, truncated
+}
+
+// readResponseBody reads the response body, removing the content codings if
+// decompression is enabled.
+func readResponseBody(r io.Reader, codings []string, data *HttpHealthArgs) ([]byte, string, bool, error) {
+	if !data.Decompress || len(codings) == 0 {
+		return readBody(r, data.MaxBodyBytes)
+	}
+	decoded, release, err := decodeBody(r, codings)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("decode body: %w", err)
+	}
+	defer release()
+	return readBody(decoded, data.MaxBodyBytes)
+}
+
+// readBody reads at most limit bytes from r, reporting whether the body was
+// longer than that. A limit of 0 or less means no limit. The rest of the body
+// is read and discarded to return the SHA-256 digest of the whole body.
+func readBody(r io.Reader, limit int64) ([]byte, string, bool, error) {
+	hash := sha256.New()
+	r = io.TeeReader(r, hash)
+	if limit <= 0 {
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return body, hex.EncodeToString(hash.Sum(nil)), false, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, "", false, err
+	}
+	truncated := int64(len(body)) > limit
+	if truncated {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return nil, "", false, err
+		}
+		body = trimIncompleteRune(body[:limit])
+	}
+	return body, hex.EncodeToString(hash.Sum(nil)), truncated, nil
+}
+
+// trimIncompleteRune removes a multi-byte UTF-8 character cut at the end of b,
+// so truncating a text body doesn't make it invalid UTF-8.
+func trimIncompleteRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// setResultBody stores the body as text when it is valid UTF-8 and as base64
+// otherwise, along with the SHA-256 digest of the whole body.
+func setResultBody(data *HttpHealthArgs, body []byte, sum string) {
+	data.ResultBodySHA256 = sum
+	if utf8.Valid(body) {
+		data.ResultBody = string(body)
+		data.ResultBodyBase64 = ""
+	} else {
+		data.ResultBody = ""
+		data.ResultBodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+}
+
+// ValidateStatusCodePattern reports whether pattern is a valid comma separated
+// list of status codes and ranges.
+func ValidateStatusCodePattern(pattern string) error {
+	_, err := parseStatusCodePattern(pattern)
+	return err
+}
+
+// MatchStatusCode reports whether code is included in pattern, a comma
+// separated list of codes and ranges. It's also used for exit codes.
+func MatchStatusCode(pattern string, code int) (bool, error) {
+	return checkStatusCode(pattern, code, nil)
+}
+
 func checkStatusCode(pattern string, code int, diag *diag.Diagnostics) (bool, error) {
-	ranges := strings.Split(pattern, ",")
+	ranges, err := parseStatusCodePattern(pattern)
+	if err != nil {
+		diagAddError(diag, "Bad status code pattern", fmt.Sprintf("Invalid pattern %q: %v", pattern, err))
+		return false, err
+	}
 	for _, r := range ranges {
+		if r.left <= code && r.right >= code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// statusCodeRange is an element of a status code pattern, a single code has
+// the same left and right bounds.
+type statusCodeRange struct {
+	left, right int
+}
+
+// parseStatusCodePattern parses every element of a comma separated list of
+// codes and ranges, so a mistake is reported even after an element that
+// would match.
+func parseStatusCodePattern(pattern string) ([]statusCodeRange, error) {
+	var ranges []statusCodeRange
+	for _, r := range strings.Split(pattern, ",") {
 		bounds := strings.Split(r, "-")
-		if len(bounds) == 2 {
+		switch len(bounds) {
+		case 1:
+			val, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("convert %q to integer: %w", bounds[0], err)
+			}
+			ranges = append(ranges, statusCodeRange{val, val})
+		case 2:
 			left, err := strconv.Atoi(bounds[0])
 			if err != nil {
-				diagAddError(diag, "Bad status code pattern", fmt.Sprintf("Can't convert %s to integer. %s", bounds[0], err))
-				return false, fmt.Errorf("convert %q to integer: %w", bounds[0], err)
+				return nil, fmt.Errorf("convert %q to integer: %w", bounds[0], err)
 			}
 			right, err := strconv.Atoi(bounds[1])
 			if err != nil {
-				diagAddError(diag, "Bad status code pattern", fmt.Sprintf("Can't convert %s to integer. %s", bounds[1], err))
-				return false, fmt.Errorf("convert %q to integer: %w", bounds[0], err)
+				return nil, fmt.Errorf("convert %q to integer: %w", bounds[1], err)
 			}
 			if left > right {
-				diagAddError(diag, "Bad status code pattern", fmt.Sprintf("Left bound %d is greater than right bound %d", left, right))
-				return false, fmt.Errorf("left bound %d is greater than right bound %d", left, right)
-			}
-			if left <= code && right >= code {
-				return true, nil
-			}
-		} else if len(bounds) == 1 {
-			val, err := strconv.Atoi(bounds[0])
-			if err != nil {
-				diagAddError(diag, "Bad status code pattern", fmt.Sprintf("Can't convert %s to integer. %s", bounds[0], err))
-				return false, fmt.Errorf("convert %q to integer: %w", bounds[0], err)
+				return nil, fmt.Errorf("left bound %d is greater than right bound %d", left, right)
 			}
-			if val == code {
-				return true, nil
-			}
-		} else {
-			diagAddError(diag, "Bad status code pattern", "Too many dashes in range pattern")
-			return false, errors.New("too many dashes in range pattern")
+			ranges = append(ranges, statusCodeRange{left, right})
+		default:
+			return nil, errors.New("too many dashes in range pattern")
 		}
 	}
-	return false, nil
+	return ranges, nil
+}
+
+func hasHeader(headers map[string][]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
 }
 
 func diagAddError(diag *diag.Diagnostics, summary string, details string) {
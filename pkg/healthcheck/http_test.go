@@ -18,7 +18,10 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 )
 
 func TestHealthCheck(t *testing.T) {
@@ -45,6 +48,22 @@ func TestHealthCheck(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "errors on invalid json_value",
+			args: &HttpHealthArgs{
+				Method:               "GET",
+				Timeout:              1000,
+				ConsecutiveSuccesses: 1,
+				StatusCode:           "200",
+				JSONPath:             "{.SomeField}",
+				JSONValue:            "[a-z",
+			},
+			mock: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"SomeField": "someValue"}`))
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -61,3 +80,141 @@ func TestHealthCheck(t *testing.T) {
 	}
 
 }
+
+func TestHealthCheckResultBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBytes    int64
+		body        []byte
+		wantBody    string
+		wantBase64  string
+		wantSHA256  string
+		wantWarning bool
+	}{
+		{
+			name:       "text body",
+			body:       []byte("hello"),
+			wantBody:   "hello",
+			wantSHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{
+			name:        "truncated body",
+			maxBytes:    4,
+			body:        []byte("hello"),
+			wantBody:    "hell",
+			wantSHA256:  "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			wantWarning: true,
+		},
+		{
+			name:        "truncated in a multi-byte character",
+			maxBytes:    2,
+			body:        []byte("héllo"),
+			wantBody:    "h",
+			wantSHA256:  "3c48591d8d098a4538f5e013dfcf406e948eac4d3277b10bf614e295d6068179",
+			wantWarning: true,
+		},
+		{
+			name:       "binary body",
+			body:       []byte{0xff, 0xfe, 0x00, 0x01},
+			wantBase64: "//4AAQ==",
+			wantSHA256: "d2ad9277baaee14856d20ec2b21f87a0cb8a7f86c6ef090fd5a082b1e85135ac",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			args := &HttpHealthArgs{
+				URL:                  server.URL,
+				Method:               "GET",
+				Timeout:              1000,
+				RequestTimeout:       500,
+				ConsecutiveSuccesses: 1,
+				StatusCode:           "200",
+				MaxBodyBytes:         tt.maxBytes,
+			}
+			diags := &diag.Diagnostics{}
+			if err := HealthCheck(context.Background(), args, diags); err != nil {
+				t.Fatalf("HealthCheck() unexpected error = %v", err)
+			}
+			if args.ResultBody != tt.wantBody {
+				t.Errorf("ResultBody = %q, want %q", args.ResultBody, tt.wantBody)
+			}
+			if args.ResultBodyBase64 != tt.wantBase64 {
+				t.Errorf("ResultBodyBase64 = %q, want %q", args.ResultBodyBase64, tt.wantBase64)
+			}
+			if args.ResultBodySHA256 != tt.wantSHA256 {
+				t.Errorf("ResultBodySHA256 = %q, want %q", args.ResultBodySHA256, tt.wantSHA256)
+			}
+			if got := diags.WarningsCount() > 0; got != tt.wantWarning {
+				t.Errorf("got warnings %v, want %v", diags.Warnings(), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestHealthCheckAllAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Host, "localhost:") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	args := &HttpHealthArgs{
+		// localhost may also resolve to ::1, which the server is not listening on
+		URL:                  strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		Method:               "GET",
+		Timeout:              1000,
+		RequestTimeout:       500,
+		ConsecutiveSuccesses: 1,
+		StatusCode:           "200",
+		AllAddresses:         true,
+		Quorum:               "any",
+	}
+	if err := HealthCheck(context.Background(), args, nil); err != nil {
+		t.Fatalf("HealthCheck() unexpected error = %v", err)
+	}
+	if !args.Passed {
+		t.Errorf("Passed = false, want true")
+	}
+	if !args.AddressResults["127.0.0.1"] {
+		t.Errorf("AddressResults = %v, want 127.0.0.1 to pass", args.AddressResults)
+	}
+}
+
+func TestMatchStatusCode(t *testing.T) {
+	tests := []struct {
+		pattern string
+		code    int
+		want    bool
+		wantErr bool
+	}{
+		{"200", 200, true, false},
+		{"200-299,304", 304, true, false},
+		{"200-299,304", 500, false, false},
+		{"200,abc", 200, false, true},
+		// the element matching the code comes before the invalid one
+		{"0-599,oops", 0, false, true},
+		{"0,abc", 0, false, true},
+		{"0,2-x", 0, false, true},
+		{"0,3-2", 0, false, true},
+		{"0,1-2-3", 0, false, true},
+	}
+	for _, tt := range tests {
+		got, err := MatchStatusCode(tt.pattern, tt.code)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("MatchStatusCode(%q, %d) = %v, %v, want %v, wantErr %v", tt.pattern, tt.code, got, err, tt.want, tt.wantErr)
+		}
+		if err := ValidateStatusCodePattern(tt.pattern); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateStatusCodePattern(%q) = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+	}
+}
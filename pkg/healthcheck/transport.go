@@ -0,0 +1,105 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// Supported values for HttpHealthArgs.HTTPVersion
+const (
+	// HTTPVersionAuto negotiates HTTP/2 over TLS when the server supports it
+	// and falls back to HTTP/1.1 otherwise.
+	HTTPVersionAuto = "auto"
+	// HTTPVersion1 only ever speaks HTTP/1.1.
+	HTTPVersion1 = "1.1"
+	// HTTPVersion2 requires HTTP/2 to be negotiated over TLS.
+	HTTPVersion2 = "2"
+	// HTTPVersionH2C speaks cleartext HTTP/2 with prior knowledge.
+	HTTPVersionH2C = "h2c"
+)
+
+// HTTPVersions lists all the accepted values for HttpHealthArgs.HTTPVersion
+var HTTPVersions = []string{HTTPVersionAuto, HTTPVersion1, HTTPVersion2, HTTPVersionH2C}
+
+// newTransport builds the transport for the requested HTTP version. If
+// dialAddr is not empty, connections are made to that address instead of the
+// resolved URL host, which is still used for SNI and the Host header.
+func newTransport(version string, tlsConfig *tls.Config, dialAddr string) (http.RoundTripper, error) {
+	dial := dialFunc(dialAddr)
+	switch version {
+	case "", HTTPVersionAuto, HTTPVersion2:
+		return &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			DialContext:       dial,
+			ForceAttemptHTTP2: true,
+			// Content codings are handled by us so that user provided
+			// Accept-Encoding headers don't leave compressed bytes in the body
+			DisableCompression: true,
+		}, nil
+	case HTTPVersion1:
+		return &http.Transport{
+			TLSClientConfig: tlsConfig,
+			DialContext:     dial,
+			// A non-nil empty map disables HTTP/2 support
+			TLSNextProto:       map[string]func(string, *tls.Conn) http.RoundTripper{},
+			DisableCompression: true,
+		}, nil
+	case HTTPVersionH2C:
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+			DisableCompression: true,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported HTTP version %q", version)
+}
+
+// dialFunc returns a dial function that connects to dialAddr, keeping the
+// port of the requested address. An empty dialAddr dials the requested
+// address as is.
+func dialFunc(dialAddr string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if dialAddr == "" {
+		return d.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		return d.DialContext(ctx, network, net.JoinHostPort(dialAddr, port))
+	}
+}
+
+// checkHTTPVersion reports whether the protocol of the response satisfies the
+// requested version.
+func checkHTTPVersion(version string, resp *http.Response) bool {
+	switch version {
+	case HTTPVersion1:
+		return resp.ProtoMajor == 1
+	case HTTPVersion2, HTTPVersionH2C:
+		return resp.ProtoMajor == 2
+	}
+	return true
+}
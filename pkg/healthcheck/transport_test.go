@@ -0,0 +1,105 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestHealthCheckHTTPVersion(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		server      func() *httptest.Server
+		version     string
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name: "auto negotiates HTTP/2 over TLS",
+			server: func() *httptest.Server {
+				s := httptest.NewUnstartedServer(handler)
+				s.EnableHTTP2 = true
+				s.StartTLS()
+				return s
+			},
+			version:     HTTPVersionAuto,
+			wantVersion: "HTTP/2.0",
+		},
+		{
+			name: "1.1 disables HTTP/2",
+			server: func() *httptest.Server {
+				s := httptest.NewUnstartedServer(handler)
+				s.EnableHTTP2 = true
+				s.StartTLS()
+				return s
+			},
+			version:     HTTPVersion1,
+			wantVersion: "HTTP/1.1",
+		},
+		{
+			name: "2 fails when the server only speaks HTTP/1.1",
+			server: func() *httptest.Server {
+				return httptest.NewTLSServer(handler)
+			},
+			version:     HTTPVersion2,
+			wantVersion: "HTTP/1.1",
+			wantErr:     true,
+		},
+		{
+			name: "h2c with prior knowledge",
+			server: func() *httptest.Server {
+				return httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+			},
+			version:     HTTPVersionH2C,
+			wantVersion: "HTTP/2.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := tt.server()
+			defer server.Close()
+
+			args := &HttpHealthArgs{
+				URL:                  server.URL,
+				Method:               "GET",
+				Timeout:              500,
+				RequestTimeout:       200,
+				Interval:             50,
+				ConsecutiveSuccesses: 1,
+				StatusCode:           "200",
+				InsecureTLS:          true,
+				HTTPVersion:          tt.version,
+			}
+			err := HealthCheck(context.Background(), args, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HealthCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if args.ResultHTTPVersion != tt.wantVersion {
+				t.Errorf("ResultHTTPVersion = %q, want %q", args.ResultHTTPVersion, tt.wantVersion)
+			}
+		})
+	}
+}
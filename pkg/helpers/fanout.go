@@ -0,0 +1,89 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+const (
+	QuorumAll = "all"
+	QuorumAny = "any"
+)
+
+// QuorumRegex matches the accepted quorum values: "all", "any" or a number
+// of addresses.
+var QuorumRegex = regexp.MustCompile(`^(all|any|[1-9][0-9]*)$`)
+
+// Quorum decides whether enough addresses passed a check.
+type Quorum struct {
+	all bool
+	min int
+}
+
+func ParseQuorum(q string) (Quorum, error) {
+	switch q {
+	case "", QuorumAll:
+		return Quorum{all: true}, nil
+	case QuorumAny:
+		return Quorum{min: 1}, nil
+	}
+	n, err := strconv.Atoi(q)
+	if err != nil || n < 1 {
+		return Quorum{}, fmt.Errorf("quorum must be %q, %q or a positive number, got %q", QuorumAll, QuorumAny, q)
+	}
+	return Quorum{min: n}, nil
+}
+
+// Satisfied reports whether passed out of total addresses is enough.
+func (q Quorum) Satisfied(passed, total int) bool {
+	if total == 0 {
+		return false
+	}
+	if q.all {
+		return passed == total
+	}
+	return passed >= q.min
+}
+
+// LookupAddresses resolves all the IP addresses of host. IP literals are
+// returned as is.
+func LookupAddresses(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+// FanOut runs fn for every address concurrently and returns the results in
+// the same order as addrs.
+func FanOut[T any](addrs []string, fn func(addr string) T) []T {
+	results := make([]T, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			results[i] = fn(addr)
+		}(i, addr)
+	}
+	wg.Wait()
+	return results
+}
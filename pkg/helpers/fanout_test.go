@@ -0,0 +1,62 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		quorum  string
+		passed  int
+		total   int
+		want    bool
+		wantErr bool
+	}{
+		{"all", 3, 3, true, false},
+		{"all", 2, 3, false, false},
+		{"", 3, 3, true, false},
+		{"any", 1, 3, true, false},
+		{"any", 0, 3, false, false},
+		{"2", 2, 3, true, false},
+		{"2", 1, 3, false, false},
+		{"all", 0, 0, false, false},
+		{"0", 0, 0, false, true},
+		{"most", 0, 0, false, true},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuorum(tt.quorum)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseQuorum(%q) error = %v, wantErr %v", tt.quorum, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := q.Satisfied(tt.passed, tt.total); got != tt.want {
+			t.Errorf("ParseQuorum(%q).Satisfied(%d, %d) = %v, want %v", tt.quorum, tt.passed, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	addrs := []string{"a", "b", "c"}
+	got := FanOut(addrs, func(addr string) string { return addr + addr })
+	if want := []string{"aa", "bb", "cc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FanOut() = %v, want %v", got, want)
+	}
+}
@@ -24,6 +24,8 @@ type RetryWindow struct {
 	Timeout              time.Duration
 	Interval             time.Duration
 	ConsecutiveSuccesses int
+	// MaxAttempts limits the number of attempts if it's positive
+	MaxAttempts int
 }
 
 type RetryResult int
@@ -32,42 +34,66 @@ const (
 	Success RetryResult = iota
 	TimeoutExceeded
 	Failure
+	AttemptsExceeded
 )
 
+// Do runs action until it succeeds ConsecutiveSuccesses times in a row, the
+// timeout expires, the context is canceled or MaxAttempts is reached. It
+// doesn't return before the last attempt has finished, so the action can
+// update shared state without synchronization.
 func (r *RetryWindow) Do(action func(attempt int, successes int) bool) RetryResult {
-	success := make(chan struct{})
-	failure := make(chan struct{})
+	return r.DoContext(func(_ context.Context, attempt int, successes int) bool {
+		return action(attempt, successes)
+	})
+}
+
+// DoContext is like Do, but passes action a context that is canceled when the
+// timeout expires, so a long attempt can be interrupted.
+func (r *RetryWindow) DoContext(action func(ctx context.Context, attempt int, successes int) bool) RetryResult {
+	ctx, cancel := context.WithCancel(r.Context)
+	defer cancel()
+
+	done := make(chan RetryResult, 1)
 	go func() {
-		attempt := 0
-		successCount := 0
-		// run a while true loop, exiting when the timeout expires
-		for {
-			select {
-			case <-r.Context.Done():
-				failure <- struct{}{}
-				return
-			default:
-				attempt++
-				if action(attempt, successCount) {
-					successCount++
-					if successCount >= r.ConsecutiveSuccesses {
-						success <- struct{}{}
-						return
-					}
-				} else {
-					successCount = 0
-				}
-				time.Sleep(r.Interval)
-			}
-		}
+		done <- r.run(ctx, action)
 	}()
 
+	timer := time.NewTimer(r.Timeout)
+	defer timer.Stop()
 	select {
-	case <-success:
-		return Success
-	case <-failure:
-		return Failure
-	case <-time.After(r.Timeout):
+	case result := <-done:
+		return result
+	case <-timer.C:
+		// stop the attempts and wait for the one in progress to finish
+		cancel()
+		<-done
 		return TimeoutExceeded
 	}
 }
+
+func (r *RetryWindow) run(ctx context.Context, action func(ctx context.Context, attempt int, successes int) bool) RetryResult {
+	attempt := 0
+	successCount := 0
+	for {
+		if ctx.Err() != nil {
+			return Failure
+		}
+		attempt++
+		if action(ctx, attempt, successCount) {
+			successCount++
+			if successCount >= r.ConsecutiveSuccesses {
+				return Success
+			}
+		} else {
+			successCount = 0
+		}
+		if r.MaxAttempts > 0 && attempt >= r.MaxAttempts {
+			return AttemptsExceeded
+		}
+		select {
+		case <-ctx.Done():
+			return Failure
+		case <-time.After(r.Interval):
+		}
+	}
+}
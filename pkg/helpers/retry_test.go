@@ -0,0 +1,73 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryWindowMaxAttempts(t *testing.T) {
+	window := RetryWindow{
+		Context:              context.Background(),
+		Timeout:              time.Second,
+		Interval:             time.Millisecond,
+		ConsecutiveSuccesses: 1,
+		MaxAttempts:          3,
+	}
+	attempts := 0
+	result := window.Do(func(attempt int, successes int) bool {
+		attempts = attempt
+		return false
+	})
+	if result != AttemptsExceeded {
+		t.Errorf("expected AttemptsExceeded, got %v", result)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	result = window.Do(func(attempt int, successes int) bool {
+		return attempt == 3
+	})
+	if result != Success {
+		t.Errorf("expected the last attempt to succeed, got %v", result)
+	}
+}
+
+func TestRetryWindowTimeoutWaitsForAttempt(t *testing.T) {
+	window := RetryWindow{
+		Context:              context.Background(),
+		Timeout:              50 * time.Millisecond,
+		Interval:             time.Millisecond,
+		ConsecutiveSuccesses: 1,
+	}
+	finished := false
+	result := window.DoContext(func(ctx context.Context, attempt int, successes int) bool {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+		return false
+	})
+	if result != TimeoutExceeded {
+		t.Errorf("expected TimeoutExceeded, got %v", result)
+	}
+	// read without synchronization, the race detector reports it if Do
+	// returned before the attempt finished
+	if !finished {
+		t.Error("expected the attempt to finish before DoContext returned")
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/helpers"
+)
+
+// addressesToCheck returns the addresses an attempt has to check: host itself,
+// or every address it resolves to when allAddresses is set.
+func addressesToCheck(ctx context.Context, host string, allAddresses bool) ([]string, error) {
+	if !allAddresses {
+		return []string{host}, nil
+	}
+	return helpers.LookupAddresses(ctx, host)
+}
+
+// addressResultsValue builds the value of the address_results attribute.
+func addressResultsValue(addrs []string, passed []bool) types.Map {
+	results := make(map[string]attr.Value, len(addrs))
+	for i, addr := range addrs {
+		results[addr] = types.BoolValue(passed[i])
+	}
+	return types.MapValueMust(types.BoolType, results)
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"sort"
+	"strings"
+)
+
+// baselineEnv are the variables passed to commands from the provider
+// environment even when it's not inherited.
+var baselineEnv = []string{"PATH", "HOME"}
+
+// commandEnv returns the environment of a command sorted by name. It's made of
+// parent, or only its baseline and allowed variables if inherit is false, and
+// env, which takes precedence. An allowed name ending in * matches every
+// variable starting with the rest of it.
+func commandEnv(parent []string, inherit bool, allowlist []string, env map[string]string) []string {
+	allowed := append(append([]string{}, baselineEnv...), allowlist...)
+	values := make(map[string]string, len(env))
+	for _, kv := range parent {
+		if kv == "" {
+			continue
+		}
+		// skip the first character, since on Windows some names start with =
+		i := strings.Index(kv[1:], "=") + 1
+		if i == 0 {
+			continue
+		}
+		k := kv[:i]
+		if inherit || envAllowed(k, allowed) {
+			values[k] = kv[i+1:]
+		}
+	}
+	for k, v := range env {
+		values[k] = v
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = k + "=" + values[k]
+	}
+	return result
+}
+
+func envAllowed(name string, allowed []string) bool {
+	for _, a := range allowed {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == a {
+			return true
+		}
+	}
+	return false
+}
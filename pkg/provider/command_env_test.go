@@ -0,0 +1,82 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandEnv(t *testing.T) {
+	parent := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/user",
+		"AWS_ACCESS_KEY_ID=AKIA",
+		"AWS_SECRET_ACCESS_KEY=secret",
+		"KUBECONFIG=/home/user/.kube/config",
+		"EMPTY=",
+		"=C:=C:\\",
+		"broken",
+		"",
+	}
+	tests := []struct {
+		name      string
+		inherit   bool
+		allowlist []string
+		env       map[string]string
+		want      []string
+	}{
+		{
+			name:    "inherit",
+			inherit: true,
+			env:     map[string]string{"ZONE": "a", "HOME": "/tmp", "APP": "web"},
+			want: []string{
+				"=C:=C:\\",
+				"APP=web",
+				"AWS_ACCESS_KEY_ID=AKIA",
+				"AWS_SECRET_ACCESS_KEY=secret",
+				"EMPTY=",
+				"HOME=/tmp",
+				"KUBECONFIG=/home/user/.kube/config",
+				"PATH=/usr/bin",
+				"ZONE=a",
+			},
+		},
+		{
+			name: "baseline only",
+			env:  map[string]string{"APP": "web"},
+			want: []string{"APP=web", "HOME=/home/user", "PATH=/usr/bin"},
+		},
+		{
+			name:      "allowlist",
+			allowlist: []string{"KUBECONFIG", "AWS_*", "MISSING"},
+			want: []string{
+				"AWS_ACCESS_KEY_ID=AKIA",
+				"AWS_SECRET_ACCESS_KEY=secret",
+				"HOME=/home/user",
+				"KUBECONFIG=/home/user/.kube/config",
+				"PATH=/usr/bin",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commandEnv(parent, tt.inherit, tt.allowlist, tt.env)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commandEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
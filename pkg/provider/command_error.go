@@ -0,0 +1,89 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/healthcheck"
+)
+
+// commandError describes why a single run of a command failed. summary is
+// used for the diagnostic and message for last_error.
+type commandError struct {
+	summary string
+	message string
+}
+
+func (e *commandError) Error() string {
+	return e.message
+}
+
+// classifyCommandError works out how a command ended from the error returned
+// by cmd.Start or cmd.Wait, and the process state that's left once it ran.
+// It returns the exit code, or -1 if the command didn't exit on its own, and
+// a nil error if the command exited, whatever its exit code was.
+func classifyCommandError(err error, state *os.ProcessState, timedOut bool) (int, *commandError) {
+	if timedOut {
+		return -1, &commandError{
+			summary: "Command timed out",
+			message: "killed by timeout",
+		}
+	}
+	if state == nil {
+		return -1, &commandError{
+			summary: "Command failed to start",
+			message: fmt.Sprintf("start failed: %v", err),
+		}
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return -1, &commandError{
+			summary: "Command killed by signal",
+			message: fmt.Sprintf("killed by signal: %v", status.Signal()),
+		}
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+		// the command exited but copying its output failed
+		return state.ExitCode(), &commandError{
+			summary: "Command output could not be read",
+			message: fmt.Sprintf("wait failed: %v", err),
+		}
+	}
+	return state.ExitCode(), nil
+}
+
+// exitCodeError returns an error if exitCode doesn't match the expected exit
+// codes pattern, or if the pattern is invalid.
+func exitCodeError(expected string, exitCode int) *commandError {
+	ok, err := healthcheck.MatchStatusCode(expected, exitCode)
+	if err != nil {
+		return &commandError{
+			summary: "Invalid exit code pattern",
+			message: fmt.Sprintf("expected_exit_codes %q is not valid: %v", expected, err),
+		}
+	}
+	if ok {
+		return nil
+	}
+	return &commandError{
+		summary: "Unexpected exit code",
+		message: fmt.Sprintf("exit %d", exitCode),
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestClassifyCommandError(t *testing.T) {
+	tests := []struct {
+		name        string
+		argv        []string
+		timeout     time.Duration
+		failStdout  bool
+		wantCode    int
+		wantSummary string
+		wantMessage string
+	}{
+		{
+			name:     "success",
+			argv:     []string{"sh", "-c", "exit 0"},
+			wantCode: 0,
+		},
+		{
+			name:     "exit",
+			argv:     []string{"sh", "-c", "exit 3"},
+			wantCode: 3,
+		},
+		{
+			name:        "signal",
+			argv:        []string{"sh", "-c", "kill -KILL $$"},
+			wantCode:    -1,
+			wantSummary: "Command killed by signal",
+			wantMessage: "killed by signal: killed",
+		},
+		{
+			name:        "timeout",
+			argv:        []string{"sleep", "5"},
+			timeout:     100 * time.Millisecond,
+			wantCode:    -1,
+			wantSummary: "Command timed out",
+			wantMessage: "killed by timeout",
+		},
+		{
+			name:        "start failure",
+			argv:        []string{"/nonexistent/checkmate"},
+			wantCode:    -1,
+			wantSummary: "Command failed to start",
+			wantMessage: "start failed: fork/exec /nonexistent/checkmate: no such file or directory",
+		},
+		{
+			name:        "output error",
+			argv:        []string{"echo", "hello"},
+			failStdout:  true,
+			wantCode:    0,
+			wantSummary: "Command output could not be read",
+			wantMessage: "wait failed: disk full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			cmd := exec.CommandContext(ctx, tt.argv[0], tt.argv[1:]...)
+			if tt.failStdout {
+				cmd.Stdout = failingWriter{}
+			}
+			err := cmd.Start()
+			if err == nil {
+				err = cmd.Wait()
+			}
+			code, cmdErr := classifyCommandError(err, cmd.ProcessState, ctx.Err() == context.DeadlineExceeded)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantSummary == "" {
+				if cmdErr != nil {
+					t.Fatalf("unexpected error %q", cmdErr)
+				}
+				return
+			}
+			if cmdErr == nil {
+				t.Fatalf("expected error %q, got none", tt.wantMessage)
+			}
+			if cmdErr.summary != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", cmdErr.summary, tt.wantSummary)
+			}
+			if cmdErr.Error() != tt.wantMessage {
+				t.Errorf("message = %q, want %q", cmdErr.Error(), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestExitCodeError(t *testing.T) {
+	if err := exitCodeError("0,2", 2); err != nil {
+		t.Errorf("unexpected error %q", err)
+	}
+	err := exitCodeError("0,2", 1)
+	if err == nil {
+		t.Fatal("expected an error for exit code 1")
+	}
+	if err.summary != "Unexpected exit code" || err.Error() != "exit 1" {
+		t.Errorf("got %q: %q", err.summary, err.Error())
+	}
+	// the invalid element comes after the one matching the exit code
+	err = exitCodeError("0,abc", 0)
+	if err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+	if err.summary != "Invalid exit code pattern" {
+		t.Errorf("got %q: %q", err.summary, err.Error())
+	}
+}
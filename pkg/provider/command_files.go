@@ -0,0 +1,108 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// FileModel is an entry of the files attribute of checkmate_local_command.
+type FileModel struct {
+	Path           types.String `tfsdk:"path"`
+	Contents       types.String `tfsdk:"contents"`
+	ContentsBase64 types.String `tfsdk:"contents_base64"`
+	Mode           types.String `tfsdk:"mode"`
+}
+
+// RelativePath returns the path of the file in the files directory, which
+// defaults to the key of the entry.
+func (f FileModel) RelativePath(key string) string {
+	if f.Path.ValueString() != "" {
+		return filepath.FromSlash(f.Path.ValueString())
+	}
+	return key
+}
+
+func (f FileModel) data() ([]byte, error) {
+	if !f.ContentsBase64.IsNull() {
+		return base64.StdEncoding.DecodeString(f.ContentsBase64.ValueString())
+	}
+	return []byte(f.Contents.ValueString()), nil
+}
+
+// fileEnvName returns the name of the env var with the path of the file with
+// the given key.
+func fileEnvName(key string) string {
+	return "CHECKMATE_FILE_" + strings.ToUpper(key)
+}
+
+// parseFileMode parses an octal file mode, which defaults to 0600.
+func parseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0600, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return os.FileMode(m), nil
+}
+
+// writeFiles writes files into dir, creating the directories they're in.
+func writeFiles(dir string, files map[string]FileModel) error {
+	keys := make([]string, 0, len(files))
+	for k := range files {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		f := files[k]
+		rel := f.RelativePath(k)
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("file %q: path %q is not a relative path inside the files directory", k, rel)
+		}
+		mode, err := parseFileMode(f.Mode.ValueString())
+		if err != nil {
+			return fmt.Errorf("file %q: invalid mode %q: %w", k, f.Mode.ValueString(), err)
+		}
+		data, err := f.data()
+		if err != nil {
+			return fmt.Errorf("file %q: invalid base64 contents: %w", k, err)
+		}
+
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return fmt.Errorf("file %q: %w", k, err)
+		}
+		if err := os.WriteFile(path, data, mode); err != nil {
+			return fmt.Errorf("file %q: %w", k, err)
+		}
+		// WriteFile doesn't change the mode of existing files, and the umask
+		// applies to new ones
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("file %q: %w", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]FileModel{
+		"script": {
+			Contents:       types.StringValue("echo hello"),
+			ContentsBase64: types.StringNull(),
+			Mode:           types.StringValue("0755"),
+		},
+		"ca": {
+			Path:           types.StringValue("certs/ca.pem"),
+			Contents:       types.StringNull(),
+			ContentsBase64: types.StringValue("Y2VydA=="),
+		},
+	}
+	if err := writeFiles(dir, files); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		path     string
+		contents string
+		mode     os.FileMode
+	}{
+		{"script", "echo hello", 0755},
+		{"certs/ca.pem", "cert", 0600},
+	} {
+		path := filepath.Join(dir, tt.path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.contents {
+			t.Errorf("%s: contents = %q, want %q", tt.path, data, tt.contents)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != tt.mode {
+			t.Errorf("%s: mode = %v, want %v", tt.path, info.Mode().Perm(), tt.mode)
+		}
+	}
+}
+
+func TestWriteFilesErrors(t *testing.T) {
+	for name, f := range map[string]FileModel{
+		"outside":  {Path: types.StringValue("../escape"), Contents: types.StringValue("x")},
+		"absolute": {Path: types.StringValue("/etc/passwd"), Contents: types.StringValue("x")},
+		"base64":   {ContentsBase64: types.StringValue("not base64!")},
+		"mode":     {Contents: types.StringValue("x"), Mode: types.StringValue("999")},
+	} {
+		if err := writeFiles(t.TempDir(), map[string]FileModel{name: f}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFileEnvName(t *testing.T) {
+	if got := fileEnvName("kube_config"); got != "CHECKMATE_FILE_KUBE_CONFIG" {
+		t.Errorf("fileEnvName() = %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	OutputLogLevelOff   = "off"
+	OutputLogLevelTrace = "trace"
+	OutputLogLevelDebug = "debug"
+	OutputLogLevelInfo  = "info"
+	OutputLogLevelWarn  = "warn"
+	OutputLogLevelError = "error"
+)
+
+// OutputLogLevels are the levels command output can be logged at.
+var OutputLogLevels = []string{
+	OutputLogLevelOff,
+	OutputLogLevelTrace,
+	OutputLogLevelDebug,
+	OutputLogLevelInfo,
+	OutputLogLevelWarn,
+	OutputLogLevelError,
+}
+
+// maxLogLineBytes is the size after which a line without a newline is logged
+// anyway, so a command can't make us buffer its whole output.
+const maxLogLineBytes = 64 * 1024
+
+// outputLogFunc returns the tflog function for level, or nil if output
+// shouldn't be logged.
+func outputLogFunc(level string) func(ctx context.Context, msg string, additionalFields ...map[string]interface{}) {
+	switch level {
+	case OutputLogLevelTrace:
+		return tflog.Trace
+	case OutputLogLevelDebug:
+		return tflog.Debug
+	case OutputLogLevelInfo:
+		return tflog.Info
+	case OutputLogLevelWarn:
+		return tflog.Warn
+	case OutputLogLevelError:
+		return tflog.Error
+	}
+	return nil
+}
+
+// lineLogger is an io.Writer that calls log for every line written to it,
+// without the trailing newline.
+type lineLogger struct {
+	log func(line string)
+	buf []byte
+}
+
+func newLineLogger(log func(line string)) *lineLogger {
+	return &lineLogger{log: log}
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(string(bytes.TrimSuffix(l.buf[:i], []byte("\r"))))
+		l.buf = l.buf[i+1:]
+	}
+	if len(l.buf) >= maxLogLineBytes {
+		l.Flush()
+	}
+	return len(p), nil
+}
+
+// Flush logs whatever is left after the last newline.
+func (l *lineLogger) Flush() {
+	if len(l.buf) > 0 {
+		l.log(string(l.buf))
+	}
+	l.buf = nil
+}
+
+// tailOutput returns the last max bytes of output, or all of it if max isn't
+// positive. It doesn't split UTF-8 sequences, so the result can be a few bytes
+// shorter than max.
+func tailOutput(output string, max int64) string {
+	if max <= 0 || int64(len(output)) <= max {
+		return output
+	}
+	i := len(output) - int(max)
+	for i < len(output) && !utf8.RuneStart(output[i]) {
+		i++
+	}
+	return output[i:]
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineLogger(t *testing.T) {
+	var lines []string
+	l := newLineLogger(func(line string) {
+		lines = append(lines, line)
+	})
+	for _, chunk := range []string{"first", " line\nsecond\r\n", "\nthird"} {
+		if n, err := l.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if want := []string{"first line", "second", ""}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("got %q before Flush, want %q", lines, want)
+	}
+	l.Flush()
+	if want := []string{"first line", "second", "", "third"}; !reflect.DeepEqual(lines, want) {
+		t.Fatalf("got %q after Flush, want %q", lines, want)
+	}
+
+	lines = nil
+	long := strings.Repeat("x", maxLogLineBytes)
+	_, _ = l.Write([]byte(long))
+	if len(lines) != 1 || lines[0] != long {
+		t.Fatalf("expected a long line to be logged without a newline, got %d lines", len(lines))
+	}
+}
+
+func TestTailOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		max    int64
+		want   string
+	}{
+		{"hello world", 0, "hello world"},
+		{"hello world", 20, "hello world"},
+		{"hello world", 5, "world"},
+		{"olá mundo", 7, " mundo"},
+		{"olá mundo", 8, "á mundo"},
+	}
+	for _, tt := range tests {
+		if got := tailOutput(tt.output, tt.max); got != tt.want {
+			t.Errorf("tailOutput(%q, %d) = %q, want %q", tt.output, tt.max, got, tt.want)
+		}
+	}
+}
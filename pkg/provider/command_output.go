@@ -0,0 +1,158 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// Supported values for output_format
+const (
+	OutputFormatJSON = "json"
+	OutputFormatYAML = "yaml"
+	OutputFormatKV   = "kv"
+)
+
+var OutputFormats = []string{OutputFormatJSON, OutputFormatYAML, OutputFormatKV}
+
+// parseCommandOutput parses the output of a command into JSON compatible
+// values, with numbers kept as json.Number.
+func parseCommandOutput(format string, output string) (interface{}, error) {
+	switch format {
+	case OutputFormatJSON:
+		return decodeJSON([]byte(output))
+	case OutputFormatYAML:
+		data, err := yaml.YAMLToJSON([]byte(output))
+		if err != nil {
+			return nil, err
+		}
+		return decodeJSON(data)
+	case OutputFormatKV:
+		return parseKeyValues(output)
+	}
+	return nil, fmt.Errorf("unsupported output format %q", format)
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	// anything after the first value is an error too
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// parseKeyValues parses key=value lines, ignoring empty lines and lines
+// starting with #.
+func parseKeyValues(output string) (interface{}, error) {
+	values := map[string]interface{}{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(text, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("line %d is not a key=value pair", line)
+		}
+		values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return values, scanner.Err()
+}
+
+// dynamicValue converts a parsed output into a Terraform value. Objects
+// become objects and arrays become tuples, so that elements can have
+// different types.
+func dynamicValue(ctx context.Context, v interface{}) (attr.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.DynamicNull(), nil
+	case string:
+		return types.StringValue(v), nil
+	case bool:
+		return types.BoolValue(v), nil
+	case json.Number:
+		f, _, err := big.ParseFloat(v.String(), 10, 512, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("parse number %q: %w", v.String(), err)
+		}
+		return types.NumberValue(f), nil
+	case []interface{}:
+		elemTypes := make([]attr.Type, len(v))
+		elems := make([]attr.Value, len(v))
+		for i, e := range v {
+			val, err := dynamicValue(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = val
+			elemTypes[i] = val.Type(ctx)
+		}
+		tuple, diags := types.TupleValue(elemTypes, elems)
+		if diags.HasError() {
+			return nil, fmt.Errorf("build tuple: %v", diags)
+		}
+		return tuple, nil
+	case map[string]interface{}:
+		attrTypes := make(map[string]attr.Type, len(v))
+		attrs := make(map[string]attr.Value, len(v))
+		for k, e := range v {
+			val, err := dynamicValue(ctx, e)
+			if err != nil {
+				return nil, err
+			}
+			attrs[k] = val
+			attrTypes[k] = val.Type(ctx)
+		}
+		obj, diags := types.ObjectValue(attrTypes, attrs)
+		if diags.HasError() {
+			return nil, fmt.Errorf("build object: %v", diags)
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// matchJSONPath applies the JSONPath expression to the parsed output and
+// reports whether the result matches re.
+func matchJSONPath(jp *jsonpath.JSONPath, re *regexp.Regexp, v interface{}) (bool, string, error) {
+	buf := new(bytes.Buffer)
+	if err := jp.Execute(buf, v); err != nil {
+		return false, "", err
+	}
+	return re.MatchString(buf.String()), buf.String(), nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		output  string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "json",
+			format: OutputFormatJSON,
+			output: `{"status": "ok", "replicas": 3, "ready": [true, null]}`,
+			want:   map[string]interface{}{"status": "ok", "replicas": json.Number("3"), "ready": []interface{}{true, nil}},
+		},
+		{
+			name:    "json with a warning",
+			format:  OutputFormatJSON,
+			output:  "Warning: deprecated\n{\"status\": \"ok\"}",
+			wantErr: true,
+		},
+		{
+			name:    "json with trailing data",
+			format:  OutputFormatJSON,
+			output:  `{"status": "ok"} done`,
+			wantErr: true,
+		},
+		{
+			name:   "yaml",
+			format: OutputFormatYAML,
+			output: "status: ok\nreplicas: 3\nitems:\n- name: a\n",
+			want:   map[string]interface{}{"status": "ok", "replicas": json.Number("3"), "items": []interface{}{map[string]interface{}{"name": "a"}}},
+		},
+		{
+			name:    "invalid yaml",
+			format:  OutputFormatYAML,
+			output:  "status: [ok",
+			wantErr: true,
+		},
+		{
+			name:   "key values",
+			format: OutputFormatKV,
+			output: "# comment\nSTATUS=ok\n\nURL = http://example.com/?a=b\n",
+			want:   map[string]interface{}{"STATUS": "ok", "URL": "http://example.com/?a=b"},
+		},
+		{
+			name:    "invalid key values",
+			format:  OutputFormatKV,
+			output:  "STATUS=ok\nready\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCommandOutput(tt.format, tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommandOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCommandOutput() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// redactedText replaces every match of a redactor.
+const redactedText = "[REDACTED]"
+
+// redactor hides secrets in command output before it's logged or stored.
+type redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// newRedactor returns a redactor for the given regular expressions and
+// literal secret values. Empty secrets are ignored.
+func newRedactor(patterns []string, secrets []string) (*redactor, error) {
+	r := &redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	var quoted []string
+	for _, s := range secrets {
+		if s != "" {
+			quoted = append(quoted, regexp.QuoteMeta(s))
+		}
+	}
+	if len(quoted) > 0 {
+		// longest first, so a secret that contains another one is redacted
+		// as a whole
+		sort.SliceStable(quoted, func(i, j int) bool {
+			return len(quoted[i]) > len(quoted[j])
+		})
+		r.patterns = append(r.patterns, regexp.MustCompile(strings.Join(quoted, "|")))
+	}
+	return r, nil
+}
+
+// Redact replaces everything in s that matches the redactor.
+func (r *redactor) Redact(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedText)
+	}
+	return s
+}
+
+// RedactValue returns a copy of a value parsed from JSON with every string in
+// it redacted.
+func (r *redactor) RedactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = r.RedactValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = r.RedactValue(e)
+		}
+		return out
+	}
+	return v
+}
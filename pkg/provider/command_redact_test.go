@@ -0,0 +1,73 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := newRedactor([]string{`token=\S+`, `ghp_[A-Za-z0-9]+`}, []string{"", "s3cr3t", "s3cr3t-and-more", "a.b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"nothing to hide", "nothing to hide"},
+		{"login token=abc123 ok", "login [REDACTED] ok"},
+		{"using ghp_AbC123 for github", "using [REDACTED] for github"},
+		{"password is s3cr3t", "password is [REDACTED]"},
+		{"password is s3cr3t-and-more", "password is [REDACTED]"},
+		{"a.b but not axb", "[REDACTED] but not axb"},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := newRedactor([]string{"("}, nil); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	r, err := newRedactor(nil, []string{"s3cr3t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := map[string]interface{}{
+		"user":     "admin",
+		"password": "s3cr3t",
+		"replicas": json.Number("3"),
+		"tokens":   []interface{}{"token s3cr3t", nil, true},
+	}
+	want := map[string]interface{}{
+		"user":     "admin",
+		"password": "[REDACTED]",
+		"replicas": json.Number("3"),
+		"tokens":   []interface{}{"token [REDACTED]", nil, true},
+	}
+	if got := r.RedactValue(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactValue() = %v, want %v", got, want)
+	}
+	if in["password"] != "s3cr3t" {
+		t.Error("RedactValue modified its input")
+	}
+}
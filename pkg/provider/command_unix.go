@@ -0,0 +1,59 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package provider
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts cmd in its own process group so that grandchildren
+// can be terminated along with it. When the context of cmd is done the group
+// gets SIGTERM, and whatever is left of it after grace gets SIGKILL, even if
+// that's after cmd.Wait returned. The returned function must be called after
+// cmd.Wait returns.
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// don't let grandchildren holding the output pipes block Wait
+	cmd.WaitDelay = grace
+
+	var mu sync.Mutex
+	var kill *time.Timer
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		mu.Lock()
+		kill = time.AfterFunc(grace, func() { _ = signalGroup(pgid, syscall.SIGKILL) })
+		mu.Unlock()
+		return signalGroup(pgid, syscall.SIGTERM)
+	}
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		// once the group is gone its id could be reused
+		if kill != nil && errors.Is(signalGroup(cmd.Process.Pid, 0), syscall.ESRCH) {
+			kill.Stop()
+		}
+	}
+}
+
+func signalGroup(pgid int, sig syscall.Signal) error {
+	return syscall.Kill(-pgid, sig)
+}
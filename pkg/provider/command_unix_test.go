@@ -0,0 +1,85 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package provider
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetProcessGroupKillsGrandchildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pids")
+	// one grandchild ignores SIGTERM and has to be killed with SIGKILL, and
+	// both keep stdout open
+	script := `sleep 30 & echo $! >> ` + pidFile + `
+sh -c 'trap "" TERM; sleep 30' & echo $! >> ` + pidFile + `
+wait`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", script)
+	var stdout strings.Builder
+	cmd.Stdout = &stdout
+	stop := setProcessGroup(cmd, 500*time.Millisecond)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	_ = cmd.Wait()
+	stop()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command took %v to finish after the timeout", elapsed)
+	}
+	// leave time for SIGKILL to be sent after the grace period
+	time.Sleep(time.Second)
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("read pids: %v", err)
+	}
+	pids := strings.Fields(string(data))
+	if len(pids) != 2 {
+		t.Fatalf("got pids %q, want 2", pids)
+	}
+	for _, p := range pids {
+		pid, err := strconv.Atoi(p)
+		if err != nil {
+			t.Fatalf("parse pid %q: %v", p, err)
+		}
+		if processRunning(pid) {
+			t.Errorf("grandchild %d is still running", pid)
+		}
+	}
+}
+
+// processRunning reports whether pid exists and is not a zombie.
+func processRunning(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// the state comes right after the command name, which is in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
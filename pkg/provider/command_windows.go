@@ -0,0 +1,29 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package provider
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup only sets the WaitDelay of cmd on Windows, where only the
+// process started by cmd is killed when its context is done.
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) func() {
+	cmd.WaitDelay = grace
+	return func() {}
+}
@@ -0,0 +1,364 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	dschema "github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	rschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/defaults"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// dataSourceExcluded are the resource attributes that data sources don't
+// have, since they only make sense for resources.
+var dataSourceExcluded = []string{"keepers", "recheck_on_refresh"}
+
+// dataSourceDescriptions replace the descriptions of resource attributes that
+// mean something else for data sources.
+var dataSourceDescriptions = map[string]string{
+	"create_anyway_on_check_failure": "If false, reading the data source fails if the check does not pass. If true, `passed` is set to false instead. Defaults to false.",
+	"id":                             "Identifier, which changes every time the data source is read",
+}
+
+// checkDataSource is a data source that runs the check of a resource every
+// time it's read. Its schema is the schema of the resource without the
+// excluded attributes, and reading it runs check on the resource model T.
+type checkDataSource[T any] struct {
+	name        string
+	description string
+	resource    resource.Resource
+	exclude     []string
+	check       func(ctx context.Context, data *T, diag *diag.Diagnostics)
+}
+
+var _ datasource.DataSourceWithValidateConfig = &checkDataSource[struct{}]{}
+
+// Metadata implements datasource.DataSource
+func (d *checkDataSource[T]) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + d.name
+}
+
+// Schema implements datasource.DataSource
+func (d *checkDataSource[T]) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	rs := d.resourceSchema(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	exclude := make(map[string]bool)
+	for _, name := range append(append([]string{}, dataSourceExcluded...), d.exclude...) {
+		exclude[name] = true
+	}
+	attributes := make(map[string]dschema.Attribute)
+	for name, a := range rs.Attributes {
+		if exclude[name] {
+			continue
+		}
+		converted, err := dataSourceAttribute(a, dataSourceDescriptions[name])
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid data source schema", fmt.Sprintf("Attribute %q: %v", name, err))
+			return
+		}
+		attributes[name] = converted
+	}
+
+	resp.Schema = dschema.Schema{
+		MarkdownDescription: d.description,
+		Attributes:          attributes,
+	}
+}
+
+// ValidateConfig implements datasource.DataSourceWithValidateConfig
+func (d *checkDataSource[T]) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	r, ok := d.resource.(resource.ResourceWithValidateConfig)
+	if !ok {
+		return
+	}
+	rs := d.resourceSchema(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	raw, err := resourceValue(ctx, rs, req.Config.Raw)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+
+	validateResp := resource.ValidateConfigResponse{}
+	r.ValidateConfig(ctx, resource.ValidateConfigRequest{Config: tfsdk.Config{Schema: rs, Raw: raw}}, &validateResp)
+	resp.Diagnostics.Append(validateResp.Diagnostics...)
+}
+
+// Read implements datasource.DataSource
+func (d *checkDataSource[T]) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	rs := d.resourceSchema(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	raw, err := resourceValue(ctx, rs, req.Config.Raw)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid configuration", err.Error())
+		return
+	}
+
+	// apply the defaults of the resource to the attributes that aren't set
+	plan := tfsdk.Plan{Schema: rs, Raw: raw}
+	for name, a := range rs.Attributes {
+		def := attributeDefault(ctx, a)
+		if def == nil {
+			continue
+		}
+		var value attr.Value
+		resp.Diagnostics.Append(plan.GetAttribute(ctx, path.Root(name), &value)...)
+		if value == nil || value.IsNull() {
+			resp.Diagnostics.Append(plan.SetAttribute(ctx, path.Root(name), def)...)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var data T
+	resp.Diagnostics.Append(plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	d.check(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state := tfsdk.State{Schema: rs, Raw: tftypes.NewValue(rs.Type().TerraformType(ctx), nil)}
+	resp.Diagnostics.Append(state.Set(ctx, &data)...)
+	resp.Diagnostics.Append(state.SetAttribute(ctx, path.Root("id"), types.StringValue(uuid.NewString()))...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	raw, err = dataSourceValue(req.Config.Raw.Type(), state.Raw)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid state", err.Error())
+		return
+	}
+	resp.State.Raw = raw
+}
+
+func (d *checkDataSource[T]) resourceSchema(ctx context.Context, diags *diag.Diagnostics) rschema.Schema {
+	resp := resource.SchemaResponse{}
+	d.resource.Schema(ctx, resource.SchemaRequest{}, &resp)
+	diags.Append(resp.Diagnostics...)
+	return resp.Schema
+}
+
+// resourceValue returns the data source object config as an object of the
+// resource schema, with the attributes the data source doesn't have set to
+// null.
+func resourceValue(ctx context.Context, rs rschema.Schema, config tftypes.Value) (tftypes.Value, error) {
+	values := map[string]tftypes.Value{}
+	if err := config.As(&values); err != nil {
+		return tftypes.Value{}, err
+	}
+	typ := rs.Type().TerraformType(ctx).(tftypes.Object)
+	result := make(map[string]tftypes.Value, len(typ.AttributeTypes))
+	for name, t := range typ.AttributeTypes {
+		if v, ok := values[name]; ok {
+			result[name] = v
+		} else {
+			result[name] = tftypes.NewValue(t, nil)
+		}
+	}
+	return tftypes.NewValue(typ, result), nil
+}
+
+// dataSourceValue returns the attributes of the resource object state that
+// are in the data source object type.
+func dataSourceValue(dsType tftypes.Type, state tftypes.Value) (tftypes.Value, error) {
+	values := map[string]tftypes.Value{}
+	if err := state.As(&values); err != nil {
+		return tftypes.Value{}, err
+	}
+	typ, ok := dsType.(tftypes.Object)
+	if !ok {
+		return tftypes.Value{}, fmt.Errorf("expected an object type, got %s", dsType)
+	}
+	result := make(map[string]tftypes.Value, len(typ.AttributeTypes))
+	for name := range typ.AttributeTypes {
+		result[name] = values[name]
+	}
+	return tftypes.NewValue(typ, result), nil
+}
+
+// attributeDefault returns the value of a top level resource attribute when
+// it's not configured, or nil if it doesn't have a default.
+func attributeDefault(ctx context.Context, a rschema.Attribute) attr.Value {
+	switch a := a.(type) {
+	case rschema.BoolAttribute:
+		if a.Default != nil {
+			resp := defaults.BoolResponse{}
+			a.Default.DefaultBool(ctx, defaults.BoolRequest{}, &resp)
+			return resp.PlanValue
+		}
+	case rschema.Int64Attribute:
+		if a.Default != nil {
+			resp := defaults.Int64Response{}
+			a.Default.DefaultInt64(ctx, defaults.Int64Request{}, &resp)
+			return resp.PlanValue
+		}
+		for _, m := range a.PlanModifiers {
+			resp := planmodifier.Int64Response{PlanValue: types.Int64Unknown()}
+			m.PlanModifyInt64(ctx, planmodifier.Int64Request{ConfigValue: types.Int64Null(), PlanValue: types.Int64Unknown(), StateValue: types.Int64Null()}, &resp)
+			if !resp.PlanValue.IsUnknown() {
+				return resp.PlanValue
+			}
+		}
+	case rschema.StringAttribute:
+		if a.Default != nil {
+			resp := defaults.StringResponse{}
+			a.Default.DefaultString(ctx, defaults.StringRequest{}, &resp)
+			return resp.PlanValue
+		}
+		for _, m := range a.PlanModifiers {
+			resp := planmodifier.StringResponse{PlanValue: types.StringUnknown()}
+			m.PlanModifyString(ctx, planmodifier.StringRequest{ConfigValue: types.StringNull(), PlanValue: types.StringUnknown(), StateValue: types.StringNull()}, &resp)
+			if !resp.PlanValue.IsUnknown() {
+				return resp.PlanValue
+			}
+		}
+	}
+	return nil
+}
+
+// dataSourceAttribute converts a resource attribute to a data source one. An
+// attribute with a default is optional and computed, so the default can be
+// set in the state.
+func dataSourceAttribute(a rschema.Attribute, description string) (dschema.Attribute, error) {
+	if description == "" {
+		description = a.GetMarkdownDescription()
+	}
+	switch a := a.(type) {
+	case rschema.BoolAttribute:
+		return dschema.BoolAttribute{
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.Int64Attribute:
+		return dschema.Int64Attribute{
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.StringAttribute:
+		return dschema.StringAttribute{
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.DynamicAttribute:
+		return dschema.DynamicAttribute{
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.ListAttribute:
+		return dschema.ListAttribute{
+			ElementType:         a.ElementType,
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.MapAttribute:
+		return dschema.MapAttribute{
+			ElementType:         a.ElementType,
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.MapNestedAttribute:
+		attributes, err := dataSourceAttributes(a.NestedObject.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		return dschema.MapNestedAttribute{
+			NestedObject: dschema.NestedAttributeObject{
+				Attributes: attributes,
+				Validators: a.NestedObject.Validators,
+			},
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	case rschema.SingleNestedAttribute:
+		attributes, err := dataSourceAttributes(a.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		return dschema.SingleNestedAttribute{
+			Attributes:          attributes,
+			MarkdownDescription: description,
+			Required:            a.Required,
+			Optional:            a.Optional,
+			Computed:            a.Computed,
+			Sensitive:           a.Sensitive,
+			Validators:          a.Validators,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute type %T", a)
+}
+
+func dataSourceAttributes(attributes map[string]rschema.Attribute) (map[string]dschema.Attribute, error) {
+	result := make(map[string]dschema.Attribute, len(attributes))
+	for name, a := range attributes {
+		converted, err := dataSourceAttribute(a, "")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		result[name] = converted
+	}
+	return result, nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+)
+
+func NewHttpHealthDataSource() datasource.DataSource {
+	r := &HttpHealthResource{}
+	return &checkDataSource[HttpHealthResourceModel]{
+		name:        "http_health",
+		description: "Runs an HTTP health check every time it's read, with the same arguments and results as the `checkmate_http_health` resource",
+		resource:    r,
+		check:       r.HealthCheck,
+	}
+}
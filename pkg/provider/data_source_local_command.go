@@ -0,0 +1,41 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func NewLocalCommandDataSource() datasource.DataSource {
+	r := &LocalCommandResource{}
+	return &checkDataSource[LocalCommandResourceModel]{
+		name:        "local_command",
+		description: "Runs a local command every time it's read, with the same arguments and results as the `checkmate_local_command` resource. The files in `files` are deleted after the command runs",
+		resource:    r,
+		exclude:     []string{"create_file", "destroy", "files_directory"},
+		check: func(ctx context.Context, data *LocalCommandResourceModel, diag *diag.Diagnostics) {
+			r.EnsureFiles(ctx, data, diag)
+			if diag.HasError() {
+				return
+			}
+			defer r.RemoveFiles(ctx, data.FilesDirectory.ValueString(), diag)
+
+			r.RunCommand(ctx, data, diag)
+		},
+	}
+}
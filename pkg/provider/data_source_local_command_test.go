@@ -0,0 +1,78 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccLocalCommandDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "checkmate_local_command" "test" {
+	command = "false"
+	timeout = 500
+}`,
+				ExpectError: regexp.MustCompile("Check failed"),
+			},
+			{
+				Config: `
+data "checkmate_local_command" "test" {
+	command         = "true"
+	output_jsonpath = "{.ready}"
+}`,
+				ExpectError: regexp.MustCompile("output_jsonpath and output_value must be set together"),
+			},
+			{
+				Config: `
+data "checkmate_local_command" "test" {
+	command = "cat $CHECKMATE_FILE_CONFIG"
+	files = {
+		config = { contents = "{\"ready\": true}" }
+	}
+	output_format   = "json"
+	output_jsonpath = "{.ready}"
+	output_value    = "true"
+}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.checkmate_local_command.test", "passed", "true"),
+					resource.TestCheckResourceAttr("data.checkmate_local_command.test", "stdout", "{\"ready\": true}"),
+					resource.TestCheckResourceAttr("data.checkmate_local_command.test", "exit_code", "0"),
+					resource.TestCheckResourceAttr("data.checkmate_local_command.test", "timeout", "10000"),
+					resource.TestCheckResourceAttrSet("data.checkmate_local_command.test", "id"),
+				),
+			},
+			{
+				Config: `
+data "checkmate_local_command" "test" {
+	command = "exit 3"
+	timeout = 500
+	create_anyway_on_check_failure = true
+}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.checkmate_local_command.test", "passed", "false"),
+					resource.TestCheckResourceAttr("data.checkmate_local_command.test", "last_error", "exit 3"),
+				),
+			},
+		},
+	})
+}
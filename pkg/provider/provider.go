@@ -57,11 +57,17 @@ func (p *CheckmateProvider) Resources(ctx context.Context) []func() resource.Res
 		NewHttpHealthResource,
 		NewLocalCommandResource,
 		NewTCPEchoResource,
+		NewTCPPortResource,
+		NewTCPScriptResource,
 	}
 }
 
 func (p *CheckmateProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewHttpHealthDataSource,
+		NewLocalCommandDataSource,
+		NewTCPEchoDataSource,
+	}
 }
 
 func New(version string) func() provider.Provider {
@@ -0,0 +1,101 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	RecheckAttempt = "attempt"
+	RecheckWindow  = "window"
+)
+
+// RecheckModes are the ways a check can be run again on refresh.
+var RecheckModes = []string{RecheckAttempt, RecheckWindow}
+
+// recheckOnRefreshAttribute is the recheck_on_refresh attribute shared by the
+// resources.
+func recheckOnRefreshAttribute() schema.StringAttribute {
+	return schema.StringAttribute{
+		MarkdownDescription: "If set, the check is run again when the resource is refreshed and `passed` is updated. With `attempt` a single attempt is made, and with `window` the check is retried like when the resource is created. If the check fails and `create_anyway_on_check_failure` is false, the resource is planned to be replaced",
+		Optional:            true,
+		Validators: []validator.String{
+			stringvalidator.OneOf(RecheckModes...),
+		},
+	}
+}
+
+// recheckFields are the fields of a resource model that control how its check
+// is run again on refresh.
+type recheckFields struct {
+	mode                 types.String
+	ignoreFailure        *types.Bool
+	consecutiveSuccesses *types.Int64
+	maxAttempts          *int
+}
+
+type recheckModel[T any] interface {
+	*T
+	recheckFields() recheckFields
+}
+
+// prepareRecheck returns a copy of data to run the check again on refresh, or
+// nil if recheck_on_refresh isn't set. Failures of the copy only update passed,
+// and with the attempt mode it makes a single attempt.
+func prepareRecheck[T any, P recheckModel[T]](data *T) *T {
+	check := *data
+	fields := P(&check).recheckFields()
+	mode := fields.mode.ValueString()
+	if mode == "" {
+		return nil
+	}
+	*fields.ignoreFailure = types.BoolValue(true)
+	if mode == RecheckAttempt {
+		*fields.maxAttempts = 1
+		*fields.consecutiveSuccesses = types.Int64Value(1)
+	}
+	return &check
+}
+
+// planRecheckReplacement plans to replace a resource whose check failed when
+// it was run again on refresh, unless create_anyway_on_check_failure is true.
+func planRecheckReplacement(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var recheck types.String
+	var ignoreFailure, passed types.Bool
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("recheck_on_refresh"), &recheck)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("create_anyway_on_check_failure"), &ignoreFailure)...)
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("passed"), &passed)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if recheck.IsNull() || recheck.IsUnknown() || ignoreFailure.ValueBool() || passed.IsNull() || passed.ValueBool() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("passed"), types.BoolUnknown())...)
+	resp.RequiresReplace = append(resp.RequiresReplace, path.Root("passed"))
+}
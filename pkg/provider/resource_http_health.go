@@ -17,25 +17,34 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	"github.com/tetratelabs/terraform-provider-checkmate/pkg/healthcheck"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/helpers"
 	"github.com/tetratelabs/terraform-provider-checkmate/pkg/modifiers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/validators"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &HttpHealthResource{}
 var _ resource.ResourceWithImportState = &HttpHealthResource{}
+var _ resource.ResourceWithModifyPlan = &HttpHealthResource{}
+var _ resource.ResourceWithValidateConfig = &HttpHealthResource{}
 
 func NewHttpHealthResource() resource.Resource {
 	return &HttpHealthResource{}
@@ -82,6 +91,7 @@ func (*HttpHealthResource) Schema(ctx context.Context, req resource.SchemaReques
 				Optional:            true,
 				Computed:            true,
 				PlanModifiers:       []planmodifier.String{modifiers.DefaultString("200")},
+				Validators:          []validator.String{validators.StatusCodePattern()},
 			},
 			"consecutive_successes": schema.Int64Attribute{
 				MarkdownDescription: "Number of consecutive successes required before the check is considered successful overall. Defaults to 1.",
@@ -105,12 +115,57 @@ func (*HttpHealthResource) Schema(ctx context.Context, req resource.SchemaReques
 			},
 			"result_body": schema.StringAttribute{
 				Computed:            true,
-				MarkdownDescription: "Result body",
+				MarkdownDescription: "Result body. Empty if the body is not valid UTF-8, in which case it is available in `result_body_base64`",
+			},
+			"result_body_base64": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Base64 encoded result body, only set when the body is not valid UTF-8",
+			},
+			"result_content_encoding": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Content-Encoding of the response, as reported by the server",
+			},
+			"decompress": schema.BoolAttribute{
+				MarkdownDescription: "Whether to request compressed responses and decode gzip, deflate, br and zstd bodies before storing and evaluating them. Default true",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"expected_content_encoding": schema.StringAttribute{
+				MarkdownDescription: "Comma separated list of content encodings the response must use, like 'br,gzip'. Use 'identity' to require an uncompressed response. If not set, the encoding is not checked",
+				Optional:            true,
+			},
+			"http_version": schema.StringAttribute{
+				MarkdownDescription: "HTTP protocol version to use. `auto` negotiates HTTP/2 over TLS when available, `1.1` only uses HTTP/1.1, `2` requires HTTP/2 to be negotiated over TLS and `h2c` uses cleartext HTTP/2 with prior knowledge for `http://` URLs. Default `auto`",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString(healthcheck.HTTPVersionAuto)},
+				Validators: []validator.String{
+					stringvalidator.OneOf(healthcheck.HTTPVersions...),
+				},
+			},
+			"result_http_version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Protocol of the last response, like `HTTP/1.1` or `HTTP/2.0`",
+			},
+			"result_body_sha256": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Hex encoded SHA-256 digest of the whole response body, even if `result_body` was truncated to `max_body_bytes`",
+			},
+			"max_body_bytes": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of bytes of the response body to keep. Longer bodies are truncated and a warning is reported. Set to 0 to disable the limit. Default 1048576",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(1048576)},
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
 			},
 			"passed": schema.BoolAttribute{
 				Computed:            true,
 				MarkdownDescription: "True if the check passed",
 			},
+			"recheck_on_refresh": recheckOnRefreshAttribute(),
 			"create_anyway_on_check_failure": schema.BoolAttribute{
 				Optional:            true,
 				MarkdownDescription: "If false, the resource will fail to create if the check does not pass. If true, the resource will be created anyway. Defaults to false.",
@@ -126,10 +181,32 @@ func (*HttpHealthResource) Schema(ctx context.Context, req resource.SchemaReques
 			"jsonpath": schema.StringAttribute{
 				Optional:            true,
 				MarkdownDescription: "Optional JSONPath expression (same syntax as kubectl jsonpath output) to apply to the result body. If the expression matches, the check will pass.",
+				Validators:          []validator.String{validators.JSONPath()},
 			},
 			"json_value": schema.StringAttribute{
 				Optional:            true,
 				MarkdownDescription: "Optional regular expression to apply to the result of the JSONPath expression. If the expression matches, the check will pass.",
+				Validators:          []validator.String{validators.Regex()},
+			},
+			"all_addresses": schema.BoolAttribute{
+				MarkdownDescription: "If true, the request is sent to every address the URL host resolves to concurrently and `quorum` decides whether the attempt passed. The URL host is still used for TLS and the Host header. Default false",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"quorum": schema.StringAttribute{
+				MarkdownDescription: "How many addresses have to pass for an attempt to pass when `all_addresses` is true. Either `all`, `any` or a number. Default `all`",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString(helpers.QuorumAll)},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(helpers.QuorumRegex, "must be \"all\", \"any\" or a positive number"),
+				},
+			},
+			"address_results": schema.MapAttribute{
+				ElementType:         types.BoolType,
+				MarkdownDescription: "Whether each checked address passed in the last attempt, keyed by address. The result body and other results are from the first address",
+				Computed:            true,
 			},
 			"keepers": schema.MapAttribute{
 				ElementType:         types.StringType,
@@ -141,30 +218,87 @@ func (*HttpHealthResource) Schema(ctx context.Context, req resource.SchemaReques
 }
 
 type HttpHealthResourceModel struct {
-	URL                  types.String `tfsdk:"url"`
-	Id                   types.String `tfsdk:"id"`
-	Method               types.String `tfsdk:"method"`
-	Timeout              types.Int64  `tfsdk:"timeout"`
-	RequestTimeout       types.Int64  `tfsdk:"request_timeout"`
-	Interval             types.Int64  `tfsdk:"interval"`
-	StatusCode           types.String `tfsdk:"status_code"`
-	ConsecutiveSuccesses types.Int64  `tfsdk:"consecutive_successes"`
-	Headers              types.Map    `tfsdk:"headers"`
-	IgnoreFailure        types.Bool   `tfsdk:"create_anyway_on_check_failure"`
-	Passed               types.Bool   `tfsdk:"passed"`
-	RequestBody          types.String `tfsdk:"request_body"`
-	ResultBody           types.String `tfsdk:"result_body"`
-	CABundle             types.String `tfsdk:"ca_bundle"`
-	InsecureTLS          types.Bool   `tfsdk:"insecure_tls"`
-	Keepers              types.Map    `tfsdk:"keepers"`
-	JSONPath             types.String `tfsdk:"jsonpath"`
-	JSONValue            types.String `tfsdk:"json_value"`
+	URL                     types.String `tfsdk:"url"`
+	Id                      types.String `tfsdk:"id"`
+	Method                  types.String `tfsdk:"method"`
+	Timeout                 types.Int64  `tfsdk:"timeout"`
+	RequestTimeout          types.Int64  `tfsdk:"request_timeout"`
+	Interval                types.Int64  `tfsdk:"interval"`
+	StatusCode              types.String `tfsdk:"status_code"`
+	ConsecutiveSuccesses    types.Int64  `tfsdk:"consecutive_successes"`
+	Headers                 types.Map    `tfsdk:"headers"`
+	IgnoreFailure           types.Bool   `tfsdk:"create_anyway_on_check_failure"`
+	Passed                  types.Bool   `tfsdk:"passed"`
+	RecheckOnRefresh        types.String `tfsdk:"recheck_on_refresh"`
+	RequestBody             types.String `tfsdk:"request_body"`
+	ResultBody              types.String `tfsdk:"result_body"`
+	ResultBodyBase64        types.String `tfsdk:"result_body_base64"`
+	ResultBodySHA256        types.String `tfsdk:"result_body_sha256"`
+	MaxBodyBytes            types.Int64  `tfsdk:"max_body_bytes"`
+	Decompress              types.Bool   `tfsdk:"decompress"`
+	ExpectedContentEncoding types.String `tfsdk:"expected_content_encoding"`
+	ResultContentEncoding   types.String `tfsdk:"result_content_encoding"`
+	HTTPVersion             types.String `tfsdk:"http_version"`
+	ResultHTTPVersion       types.String `tfsdk:"result_http_version"`
+	CABundle                types.String `tfsdk:"ca_bundle"`
+	InsecureTLS             types.Bool   `tfsdk:"insecure_tls"`
+	Keepers                 types.Map    `tfsdk:"keepers"`
+	JSONPath                types.String `tfsdk:"jsonpath"`
+	JSONValue               types.String `tfsdk:"json_value"`
+	AllAddresses            types.Bool   `tfsdk:"all_addresses"`
+	Quorum                  types.String `tfsdk:"quorum"`
+	AddressResults          types.Map    `tfsdk:"address_results"`
+
+	// maxAttempts limits the attempts of the check if it's positive
+	maxAttempts int
+}
+
+// recheckFields implements recheckModel
+func (m *HttpHealthResourceModel) recheckFields() recheckFields {
+	return recheckFields{
+		mode:                 m.RecheckOnRefresh,
+		ignoreFailure:        &m.IgnoreFailure,
+		consecutiveSuccesses: &m.ConsecutiveSuccesses,
+		maxAttempts:          &m.maxAttempts,
+	}
 }
 
 func (r *HttpHealthResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_http_health"
 }
 
+func (r *HttpHealthResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data HttpHealthResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.JSONPath.IsNull() != data.JSONValue.IsNull() {
+		missing := "json_value"
+		if data.JSONPath.IsNull() {
+			missing = "jsonpath"
+		}
+		resp.Diagnostics.AddAttributeError(path.Root(missing), "Missing attribute", "jsonpath and json_value must be set together")
+	}
+
+	if !data.CABundle.IsNull() && data.InsecureTLS.ValueBool() {
+		resp.Diagnostics.AddAttributeError(path.Root("insecure_tls"), "Conflicting configuration", "ca_bundle and insecure_tls can't be used together, as the CA bundle would be ignored")
+	}
+
+	if !data.URL.IsUnknown() {
+		if u, err := url.Parse(data.URL.ValueString()); err == nil {
+			switch {
+			case data.HTTPVersion.ValueString() == healthcheck.HTTPVersionH2C && u.Scheme == "https":
+				resp.Diagnostics.AddAttributeError(path.Root("http_version"), "Conflicting configuration", "http_version h2c speaks cleartext HTTP/2 and can't be used with an https URL, use 2 instead")
+			case data.HTTPVersion.ValueString() == healthcheck.HTTPVersion2 && u.Scheme == "http":
+				resp.Diagnostics.AddAttributeError(path.Root("http_version"), "Conflicting configuration", "http_version 2 negotiates HTTP/2 over TLS and can't be used with an http URL, use h2c for cleartext HTTP/2 instead")
+			}
+		}
+	}
+}
+
 func (r *HttpHealthResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data HttpHealthResourceModel
 
@@ -190,20 +324,27 @@ func (r *HttpHealthResource) HealthCheck(ctx context.Context, data *HttpHealthRe
 		diag.Append(data.Headers.ElementsAs(ctx, &tmp, false)...)
 	}
 	args := healthcheck.HttpHealthArgs{
-		URL:                  data.URL.ValueString(),
-		Method:               data.Method.ValueString(),
-		Timeout:              data.Timeout.ValueInt64(),
-		RequestTimeout:       data.RequestTimeout.ValueInt64(),
-		Interval:             data.Interval.ValueInt64(),
-		StatusCode:           data.StatusCode.ValueString(),
-		ConsecutiveSuccesses: data.ConsecutiveSuccesses.ValueInt64(),
-		Headers:              tmp,
-		IgnoreFailure:        data.IgnoreFailure.ValueBool(),
-		RequestBody:          data.RequestBody.ValueString(),
-		CABundle:             data.CABundle.ValueString(),
-		InsecureTLS:          data.InsecureTLS.ValueBool(),
-		JSONPath:             data.JSONPath.ValueString(),
-		JSONValue:            data.JSONValue.ValueString(),
+		URL:                     data.URL.ValueString(),
+		Method:                  data.Method.ValueString(),
+		Timeout:                 data.Timeout.ValueInt64(),
+		RequestTimeout:          data.RequestTimeout.ValueInt64(),
+		Interval:                data.Interval.ValueInt64(),
+		StatusCode:              data.StatusCode.ValueString(),
+		ConsecutiveSuccesses:    data.ConsecutiveSuccesses.ValueInt64(),
+		Headers:                 tmp,
+		IgnoreFailure:           data.IgnoreFailure.ValueBool(),
+		RequestBody:             data.RequestBody.ValueString(),
+		MaxBodyBytes:            data.MaxBodyBytes.ValueInt64(),
+		Decompress:              data.Decompress.ValueBool(),
+		ExpectedContentEncoding: data.ExpectedContentEncoding.ValueString(),
+		HTTPVersion:             data.HTTPVersion.ValueString(),
+		CABundle:                data.CABundle.ValueString(),
+		InsecureTLS:             data.InsecureTLS.ValueBool(),
+		JSONPath:                data.JSONPath.ValueString(),
+		JSONValue:               data.JSONValue.ValueString(),
+		AllAddresses:            data.AllAddresses.ValueBool(),
+		Quorum:                  data.Quorum.ValueString(),
+		MaxAttempts:             data.maxAttempts,
 	}
 
 	err := healthcheck.HealthCheck(ctx, &args, diag)
@@ -213,6 +354,13 @@ func (r *HttpHealthResource) HealthCheck(ctx context.Context, data *HttpHealthRe
 
 	data.Passed = types.BoolValue(args.Passed)
 	data.ResultBody = types.StringValue(args.ResultBody)
+	data.ResultBodyBase64 = types.StringValue(args.ResultBodyBase64)
+	data.ResultBodySHA256 = types.StringValue(args.ResultBodySHA256)
+	data.ResultContentEncoding = types.StringValue(args.ResultContentEncoding)
+	data.ResultHTTPVersion = types.StringValue(args.ResultHTTPVersion)
+	addressResults, diags := types.MapValueFrom(ctx, types.BoolType, args.AddressResults)
+	diag.Append(diags...)
+	data.AddressResults = addressResults
 }
 
 func (r *HttpHealthResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -224,9 +372,22 @@ func (r *HttpHealthResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	if check := prepareRecheck(&data); check != nil {
+		r.HealthCheck(ctx, check, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Passed = check.Passed
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
 }
 
+// ModifyPlan implements resource.ResourceWithModifyPlan
+func (*HttpHealthResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	planRecheckReplacement(ctx, req, resp)
+}
+
 func (r *HttpHealthResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var data HttpHealthResourceModel
 
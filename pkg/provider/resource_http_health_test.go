@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
@@ -73,6 +74,50 @@ func TestAccHttpHealthResource(t *testing.T) {
 	})
 }
 
+func TestAccHttpHealthResourceValidation(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testHttpHealthResourceValidation("test_jsonpath_alone", `jsonpath = "{.status}"`),
+				ExpectError: regexp.MustCompile("jsonpath and json_value must be set together"),
+			},
+			{
+				Config: testHttpHealthResourceValidation("test_ca_and_insecure", `ca_bundle = "bundle"
+	insecure_tls = true`),
+				ExpectError: regexp.MustCompile("ca_bundle and insecure_tls can't be used together"),
+			},
+			{
+				Config: `
+resource "checkmate_http_health" "test_h2c_https" {
+	url = "https://127.0.0.1:1"
+	http_version = "h2c"
+}`,
+				ExpectError: regexp.MustCompile("http_version h2c speaks cleartext HTTP/2"),
+			},
+			{
+				Config:      testHttpHealthResourceValidation("test_h2_http", `http_version = "2"`),
+				ExpectError: regexp.MustCompile("http_version 2 negotiates HTTP/2 over TLS"),
+			},
+			{
+				Config:      testHttpHealthResourceValidation("test_bad_status_code", `status_code = "200-"`),
+				ExpectError: regexp.MustCompile("Invalid status code pattern"),
+			},
+			{
+				Config: testHttpHealthResourceValidation("test_bad_jsonpath", `jsonpath = "{.foo"
+	json_value = "ok"`),
+				ExpectError: regexp.MustCompile("Invalid JSONPath expression"),
+			},
+			{
+				Config: testHttpHealthResourceValidation("test_bad_json_value", `jsonpath = "{.foo}"
+	json_value = "[a-z"`),
+				ExpectError: regexp.MustCompile("Invalid regex"),
+			},
+		},
+	})
+}
+
 func TestStatusCodePattern(t *testing.T) {
 	tests := []struct {
 		pattern string
@@ -189,3 +234,11 @@ func checkPassed(value string) error {
 	}
 	return fmt.Errorf("test did not pass")
 }
+
+func testHttpHealthResourceValidation(name, extra string) string {
+	return fmt.Sprintf(`
+resource "checkmate_http_health" %q {
+	url = "http://127.0.0.1:1"
+	%s
+}`, name, extra)
+}
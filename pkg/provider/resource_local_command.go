@@ -17,28 +17,45 @@ package provider
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	tfpath "github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/client-go/util/jsonpath"
 
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/healthcheck"
 	"github.com/tetratelabs/terraform-provider-checkmate/pkg/helpers"
 	"github.com/tetratelabs/terraform-provider-checkmate/pkg/modifiers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/validators"
 )
 
 var _ resource.Resource = &LocalCommandResource{}
 var _ resource.ResourceWithImportState = &LocalCommandResource{}
+var _ resource.ResourceWithModifyPlan = &LocalCommandResource{}
+var _ resource.ResourceWithValidateConfig = &LocalCommandResource{}
 
 type LocalCommandResource struct{}
 
@@ -49,8 +66,28 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 
 		Attributes: map[string]schema.Attribute{
 			"command": schema.StringAttribute{
-				MarkdownDescription: "The command to run (passed to `sh -c`)",
-				Required:            true,
+				MarkdownDescription: "The command to run, passed to `interpreter`. Exactly one of `command` or `args` must be set",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.ExactlyOneOf(tfpath.MatchRoot("args")),
+				},
+			},
+			"interpreter": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "The interpreter and its arguments used to run `command`, which is appended as the last argument. For example `[\"bash\", \"-euo\", \"pipefail\", \"-c\"]` or `[\"python3\", \"-c\"]`. Defaults to `[\"sh\", \"-c\"]`",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+					listvalidator.ConflictsWith(tfpath.MatchRoot("args")),
+				},
+			},
+			"args": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "The program to run followed by its arguments. The program is executed directly, without a shell",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
 			},
 			"timeout": schema.Int64Attribute{
 				MarkdownDescription: "Overall timeout in milliseconds for the check before giving up, default 10000",
@@ -64,6 +101,15 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Computed:            true,
 				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(5000)},
 			},
+			"termination_grace_period": schema.Int64Attribute{
+				MarkdownDescription: "Time in milliseconds the processes started by an attempt get to exit after receiving SIGTERM when `command_timeout` expires, before they are killed with SIGKILL. Commands run in their own process group, so this includes their children. Default 2000",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(2000)},
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
 			"interval": schema.Int64Attribute{
 				MarkdownDescription: "Interval in milliseconds between attemps. Default 200",
 				Optional:            true,
@@ -76,6 +122,18 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Computed:            true,
 				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(1)},
 			},
+			"stdin": schema.StringAttribute{
+				MarkdownDescription: "Data written to the standard input of the command in every attempt",
+				Optional:            true,
+				Sensitive:           true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(tfpath.MatchRoot("stdin_file")),
+				},
+			},
+			"stdin_file": schema.StringAttribute{
+				MarkdownDescription: "Path to a file whose contents are written to the standard input of the command in every attempt",
+				Optional:            true,
+			},
 			"working_directory": schema.StringAttribute{
 				MarkdownDescription: "Working directory where the command will be run. Defaults to the current working directory",
 				Optional:            true,
@@ -84,11 +142,28 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 			},
 			"env": schema.MapAttribute{
 				ElementType:         types.StringType,
-				MarkdownDescription: "Map of environment variables to apply to the command. Inherits the parent environment",
+				MarkdownDescription: "Map of environment variables to apply to the command, on top of the environment of the provider if `inherit_env` is true",
+				Optional:            true,
+			},
+			"inherit_env": schema.BoolAttribute{
+				MarkdownDescription: "If true, the command inherits the whole environment of the provider. If false, it only gets `PATH`, `HOME` and the variables in `env_allowlist`. Defaults to true.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"env_allowlist": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Names of variables passed from the environment of the provider to the command when `inherit_env` is false. A name ending in `*` matches every variable starting with the rest of it, like `AWS_*`",
 				Optional:            true,
 			},
+			"secret_env": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Map of environment variables to apply to the command, like `env`. Their values are redacted from the output before it's logged or stored",
+				Optional:            true,
+				Sensitive:           true,
+			},
 			"create_file": schema.SingleNestedAttribute{
-				MarkdownDescription: "Ensure a file exists with the following contents. The path to this file will be available in the env var CHECKMATE_FILEPATH",
+				MarkdownDescription: "Ensure a file exists with the following contents. The path to this file will be available in the env var CHECKMATE_FILEPATH. If the file is removed, the resource is created again on the next apply",
 				Optional:            true,
 				Attributes: map[string]schema.Attribute{
 					"contents": schema.StringAttribute{
@@ -96,9 +171,26 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 						Required:            true,
 					},
 					"name": schema.StringAttribute{
-						MarkdownDescription: "Name of the created file.",
+						MarkdownDescription: "Name of the created file. Unless `exact_name` is true, it's used as a pattern and a random string is added to it, or replaces the last `*` in it.",
 						Required:            true,
 					},
+					"exact_name": schema.BoolAttribute{
+						MarkdownDescription: "If true, the file is created with exactly `name`, replacing any existing file. Defaults to false.",
+						Optional:            true,
+					},
+					"mode": schema.StringAttribute{
+						MarkdownDescription: "Permissions of the file as an octal string like `0755`. Defaults to `0600`",
+						Optional:            true,
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(regexp.MustCompile(`^0?[0-7]{3}$`), "must be an octal file mode like 0755"),
+						},
+					},
+					"delete_on_destroy": schema.BoolAttribute{
+						MarkdownDescription: "If true, the file is deleted when the resource is destroyed or the file is replaced. Defaults to true.",
+						Optional:            true,
+						Computed:            true,
+						Default:             booldefault.StaticBool(true),
+					},
 					"path": schema.StringAttribute{
 						MarkdownDescription: "Path to the file that was created",
 						Computed:            true,
@@ -113,6 +205,140 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 					},
 				},
 			},
+			"files": schema.MapNestedAttribute{
+				MarkdownDescription: "Files to create in a temporary directory for the resource, keyed by a name made of letters, digits and underscores. The path to the directory is available in the env var CHECKMATE_DIR, and the path to each file in CHECKMATE_FILE_<NAME>, with the name in upper case. The directory is deleted when the resource is destroyed, and the resource is created again if it's removed",
+				Optional:            true,
+				Validators: []validator.Map{
+					mapvalidator.KeysAre(stringvalidator.RegexMatches(regexp.MustCompile(`^[A-Za-z0-9_]+$`), "must only contain letters, digits and underscores")),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							MarkdownDescription: "Path of the file relative to the directory, like `certs/ca.pem`. Defaults to the name of the file",
+							Optional:            true,
+						},
+						"contents": schema.StringAttribute{
+							MarkdownDescription: "Contents of the file. Exactly one of `contents` or `contents_base64` must be set",
+							Optional:            true,
+							Sensitive:           true,
+							Validators: []validator.String{
+								stringvalidator.ExactlyOneOf(tfpath.MatchRelative().AtParent().AtName("contents_base64")),
+							},
+						},
+						"contents_base64": schema.StringAttribute{
+							MarkdownDescription: "Base64 encoded contents of the file, for binary files",
+							Optional:            true,
+							Sensitive:           true,
+							Validators:          []validator.String{validators.Base64()},
+						},
+						"mode": schema.StringAttribute{
+							MarkdownDescription: "Permissions of the file as an octal string like `0755`. Defaults to `0600`",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.RegexMatches(regexp.MustCompile(`^0?[0-7]{3}$`), "must be an octal file mode like 0755"),
+							},
+						},
+					},
+				},
+			},
+			"files_directory": schema.StringAttribute{
+				MarkdownDescription: "Path to the directory with the files in `files`",
+				Computed:            true,
+			},
+			"expected_exit_codes": schema.StringAttribute{
+				MarkdownDescription: "Exit codes the command is expected to return. Can be a comma separated list of codes and ranges like '0,2-3'. Default 0",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString("0")},
+				Validators:          []validator.String{validators.ExitCodePattern()},
+			},
+			"stdout_regex": schema.StringAttribute{
+				MarkdownDescription: "Optional regular expression the standard output must match for the check to pass",
+				Optional:            true,
+				Validators:          []validator.String{validators.Regex()},
+			},
+			"stderr_regex": schema.StringAttribute{
+				MarkdownDescription: "Optional regular expression the standard error output must match for the check to pass",
+				Optional:            true,
+				Validators:          []validator.String{validators.Regex()},
+			},
+			"stdout_contains": schema.StringAttribute{
+				MarkdownDescription: "Optional string the standard output must contain for the check to pass",
+				Optional:            true,
+			},
+			"fail_on_stderr": schema.BoolAttribute{
+				MarkdownDescription: "If true, the check fails if the command writes anything to the standard error output. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"output_format": schema.StringAttribute{
+				MarkdownDescription: "Format of the standard output, one of `json`, `yaml` or `kv` for `key=value` lines. If set, the output is parsed into `output` and the attempt fails if it can't be parsed",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(OutputFormats...),
+				},
+			},
+			"output_jsonpath": schema.StringAttribute{
+				MarkdownDescription: "Optional JSONPath expression (same syntax as kubectl jsonpath output) to apply to the parsed output. Requires `output_format` and `output_value`",
+				Optional:            true,
+				Validators:          []validator.String{validators.JSONPath()},
+			},
+			"output_value": schema.StringAttribute{
+				MarkdownDescription: "Regular expression the result of `output_jsonpath` must match for the check to pass",
+				Optional:            true,
+				Validators:          []validator.String{validators.Regex()},
+			},
+			"output": schema.DynamicAttribute{
+				MarkdownDescription: "Standard output of the command parsed according to `output_format`",
+				Computed:            true,
+			},
+			"output_log_level": schema.StringAttribute{
+				MarkdownDescription: "Level at which the standard output and error of the command are streamed line by line to the Terraform logs while it runs. One of `off`, `trace`, `debug`, `info`, `warn` or `error`. Default `debug`",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString(OutputLogLevelDebug)},
+				Validators: []validator.String{
+					stringvalidator.OneOf(OutputLogLevels...),
+				},
+			},
+			"max_output_bytes": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of bytes of `stdout` and `stderr` stored in the state. Only the last bytes of the output are kept. Assertions are applied to the whole output. Defaults to no limit",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"omit_output": schema.BoolAttribute{
+				MarkdownDescription: "If true, `stdout` and `stderr` are not stored in the state. Assertions are still applied to them. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"sensitive_output": schema.BoolAttribute{
+				MarkdownDescription: "If true, the output of the command is stored in `sensitive_stdout` and `sensitive_stderr` instead of `stdout` and `stderr`, it isn't logged and `output` isn't set. Assertions are still applied to it. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"redact_patterns": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Regular expressions whose matches are replaced with `[REDACTED]` in the output of the command before it's logged or stored. Assertions are applied to the output before it's redacted",
+				Optional:            true,
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(validators.Regex()),
+				},
+			},
+			"sensitive_stdout": schema.StringAttribute{
+				MarkdownDescription: "Standard output of the command if `sensitive_output` is true",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"sensitive_stderr": schema.StringAttribute{
+				MarkdownDescription: "Standard error output of the command if `sensitive_output` is true",
+				Computed:            true,
+				Sensitive:           true,
+			},
 			"stdout": schema.StringAttribute{
 				MarkdownDescription: "Standard output of the command",
 				Computed:            true,
@@ -121,10 +347,67 @@ func (*LocalCommandResource) Schema(ctx context.Context, req resource.SchemaRequ
 				MarkdownDescription: "Standard error output of the command",
 				Computed:            true,
 			},
+			"exit_code": schema.Int64Attribute{
+				MarkdownDescription: "Exit code of the command in the last attempt. -1 if the command didn't exit normally, for example because it couldn't be started or was killed",
+				Computed:            true,
+			},
+			"attempts": schema.Int64Attribute{
+				MarkdownDescription: "Number of attempts made",
+				Computed:            true,
+			},
+			"duration_ms": schema.Int64Attribute{
+				MarkdownDescription: "Duration in milliseconds of the last attempt",
+				Computed:            true,
+			},
+			"last_error": schema.StringAttribute{
+				MarkdownDescription: "Why the last attempt failed: `exit 3` for an unexpected exit code, `killed by signal: killed`, `killed by timeout`, `start failed: ...` or a description of the output that didn't match. Empty if it passed",
+				Computed:            true,
+			},
 			"passed": schema.BoolAttribute{
 				Computed:            true,
 				MarkdownDescription: "True if the check passed",
 			},
+			"destroy": schema.SingleNestedAttribute{
+				MarkdownDescription: "Command to run when the resource is destroyed, like waiting for a load balancer to deregister a target. It's retried like the check, and runs with the same interpreter, environment and files",
+				Optional:            true,
+				Attributes: map[string]schema.Attribute{
+					"command": schema.StringAttribute{
+						MarkdownDescription: "The command to run, passed to `interpreter`",
+						Required:            true,
+					},
+					"timeout": schema.Int64Attribute{
+						MarkdownDescription: "Overall timeout in milliseconds for the command to succeed before giving up, default 10000",
+						Optional:            true,
+						Computed:            true,
+						Default:             int64default.StaticInt64(10000),
+					},
+					"command_timeout": schema.Int64Attribute{
+						MarkdownDescription: "Timeout for an individual attempt. Default 5000ms",
+						Optional:            true,
+						Computed:            true,
+						Default:             int64default.StaticInt64(5000),
+					},
+					"interval": schema.Int64Attribute{
+						MarkdownDescription: "Interval in milliseconds between attemps. Default 200",
+						Optional:            true,
+						Computed:            true,
+						Default:             int64default.StaticInt64(200),
+					},
+					"consecutive_successes": schema.Int64Attribute{
+						MarkdownDescription: "Number of consecutive successes required before the command is considered successful. Defaults to 1.",
+						Optional:            true,
+						Computed:            true,
+						Default:             int64default.StaticInt64(1),
+					},
+					"ignore_failure": schema.BoolAttribute{
+						MarkdownDescription: "If true, the resource is destroyed even if the command doesn't succeed. Defaults to false.",
+						Optional:            true,
+						Computed:            true,
+						Default:             booldefault.StaticBool(false),
+					},
+				},
+			},
+			"recheck_on_refresh": recheckOnRefreshAttribute(),
 			"create_anyway_on_check_failure": schema.BoolAttribute{
 				Optional:            true,
 				MarkdownDescription: "If false, the resource will fail to create if the check does not pass. If true, the resource will be created anyway. Defaults to false.",
@@ -147,24 +430,125 @@ type CreateFileModel struct {
 	Path                types.String `tfsdk:"path"`
 	UseWorkingDirectory types.Bool   `tfsdk:"use_working_dir"`
 	Name                types.String `tfsdk:"name"`
+	ExactName           types.Bool   `tfsdk:"exact_name"`
+	Mode                types.String `tfsdk:"mode"`
+	DeleteOnDestroy     types.Bool   `tfsdk:"delete_on_destroy"`
 	CreateDirectory     types.Bool   `tfsdk:"create_directory"`
 }
 
+type DestroyModel struct {
+	Command              types.String `tfsdk:"command"`
+	Timeout              types.Int64  `tfsdk:"timeout"`
+	CommandTimeout       types.Int64  `tfsdk:"command_timeout"`
+	Interval             types.Int64  `tfsdk:"interval"`
+	ConsecutiveSuccesses types.Int64  `tfsdk:"consecutive_successes"`
+	IgnoreFailure        types.Bool   `tfsdk:"ignore_failure"`
+}
+
 type LocalCommandResourceModel struct {
 	Id                   types.String     `tfsdk:"id"`
 	Command              types.String     `tfsdk:"command"`
+	Interpreter          types.List       `tfsdk:"interpreter"`
+	Args                 types.List       `tfsdk:"args"`
 	Timeout              types.Int64      `tfsdk:"timeout"`
 	CommandTimeout       types.Int64      `tfsdk:"command_timeout"`
+	GracePeriod          types.Int64      `tfsdk:"termination_grace_period"`
 	Interval             types.Int64      `tfsdk:"interval"`
 	ConsecutiveSuccesses types.Int64      `tfsdk:"consecutive_successes"`
+	Stdin                types.String     `tfsdk:"stdin"`
+	StdinFile            types.String     `tfsdk:"stdin_file"`
 	WorkDir              types.String     `tfsdk:"working_directory"`
+	ExpectedExitCodes    types.String     `tfsdk:"expected_exit_codes"`
+	StdoutRegex          types.String     `tfsdk:"stdout_regex"`
+	StderrRegex          types.String     `tfsdk:"stderr_regex"`
+	StdoutContains       types.String     `tfsdk:"stdout_contains"`
+	FailOnStderr         types.Bool       `tfsdk:"fail_on_stderr"`
+	OutputFormat         types.String     `tfsdk:"output_format"`
+	OutputJSONPath       types.String     `tfsdk:"output_jsonpath"`
+	OutputValue          types.String     `tfsdk:"output_value"`
+	Output               types.Dynamic    `tfsdk:"output"`
+	OutputLogLevel       types.String     `tfsdk:"output_log_level"`
+	MaxOutputBytes       types.Int64      `tfsdk:"max_output_bytes"`
+	OmitOutput           types.Bool       `tfsdk:"omit_output"`
+	SensitiveOutput      types.Bool       `tfsdk:"sensitive_output"`
+	RedactPatterns       types.List       `tfsdk:"redact_patterns"`
+	SensitiveStdout      types.String     `tfsdk:"sensitive_stdout"`
+	SensitiveStderr      types.String     `tfsdk:"sensitive_stderr"`
 	Stdout               types.String     `tfsdk:"stdout"`
 	Stderr               types.String     `tfsdk:"stderr"`
+	ExitCode             types.Int64      `tfsdk:"exit_code"`
+	Attempts             types.Int64      `tfsdk:"attempts"`
+	Duration             types.Int64      `tfsdk:"duration_ms"`
+	LastError            types.String     `tfsdk:"last_error"`
 	Env                  types.Map        `tfsdk:"env"`
+	InheritEnv           types.Bool       `tfsdk:"inherit_env"`
+	EnvAllowlist         types.List       `tfsdk:"env_allowlist"`
+	SecretEnv            types.Map        `tfsdk:"secret_env"`
 	CreateFile           *CreateFileModel `tfsdk:"create_file"`
+	Files                types.Map        `tfsdk:"files"`
+	FilesDirectory       types.String     `tfsdk:"files_directory"`
+	Destroy              *DestroyModel    `tfsdk:"destroy"`
 	IgnoreFailure        types.Bool       `tfsdk:"create_anyway_on_check_failure"`
 	Passed               types.Bool       `tfsdk:"passed"`
+	RecheckOnRefresh     types.String     `tfsdk:"recheck_on_refresh"`
 	Keepers              types.Map        `tfsdk:"keepers"`
+
+	// maxAttempts limits the attempts of the check if it's positive
+	maxAttempts int
+}
+
+// recheckFields implements recheckModel
+func (m *LocalCommandResourceModel) recheckFields() recheckFields {
+	return recheckFields{
+		mode:                 m.RecheckOnRefresh,
+		ignoreFailure:        &m.IgnoreFailure,
+		consecutiveSuccesses: &m.ConsecutiveSuccesses,
+		maxAttempts:          &m.maxAttempts,
+	}
+}
+
+// ValidateConfig implements resource.ResourceWithValidateConfig
+func (*LocalCommandResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data LocalCommandResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.OutputJSONPath.IsNull() != data.OutputValue.IsNull() {
+		missing := "output_value"
+		if data.OutputJSONPath.IsNull() {
+			missing = "output_jsonpath"
+		}
+		resp.Diagnostics.AddAttributeError(tfpath.Root(missing), "Missing attribute", "output_jsonpath and output_value must be set together")
+	}
+	if !data.OutputJSONPath.IsNull() && data.OutputFormat.IsNull() {
+		resp.Diagnostics.AddAttributeError(tfpath.Root("output_format"), "Missing attribute", "output_format is required to apply output_jsonpath")
+	}
+	if !data.Files.IsNull() && !data.Files.IsUnknown() {
+		files := make(map[string]FileModel)
+		resp.Diagnostics.Append(data.Files.ElementsAs(ctx, &files, false)...)
+		keys := make([]string, 0, len(files))
+		for k := range files {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		envNames := make(map[string]string)
+		for _, k := range keys {
+			if f := files[k]; !f.Path.IsUnknown() && !filepath.IsLocal(f.RelativePath(k)) {
+				resp.Diagnostics.AddAttributeError(tfpath.Root("files").AtMapKey(k).AtName("path"), "Invalid path", fmt.Sprintf("%q must be a relative path inside the files directory", f.Path.ValueString()))
+			}
+			name := fileEnvName(k)
+			if other, ok := envNames[name]; ok {
+				resp.Diagnostics.AddAttributeError(tfpath.Root("files").AtMapKey(k), "Conflicting file names", fmt.Sprintf("%q and %q only differ in case and would both set %s", other, k, name))
+			}
+			envNames[name] = k
+		}
+	}
+	if !data.EnvAllowlist.IsNull() && !data.InheritEnv.IsUnknown() && (data.InheritEnv.IsNull() || data.InheritEnv.ValueBool()) {
+		resp.Diagnostics.AddAttributeError(tfpath.Root("env_allowlist"), "Conflicting configuration", "env_allowlist only applies when inherit_env is false")
+	}
 }
 
 // ImportState implements resource.ResourceWithImportState
@@ -188,8 +572,16 @@ func (r *LocalCommandResource) Create(ctx context.Context, req resource.CreateRe
 		return
 	}
 
+	r.EnsureFiles(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		r.DiscardFile(ctx, data.CreateFile, nil, &resp.Diagnostics)
+		return
+	}
+
 	r.RunCommand(ctx, &data, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
+		r.DiscardFile(ctx, data.CreateFile, nil, &resp.Diagnostics)
+		r.RemoveFiles(ctx, data.FilesDirectory.ValueString(), &resp.Diagnostics)
 		return
 	}
 
@@ -200,12 +592,20 @@ func (r *LocalCommandResource) RunCommand(ctx context.Context, data *LocalComman
 	data.Passed = types.BoolValue(false)
 	data.Stdout = types.StringNull()
 	data.Stderr = types.StringNull()
+	data.SensitiveStdout = types.StringNull()
+	data.SensitiveStderr = types.StringNull()
+	data.ExitCode = types.Int64Value(-1)
+	data.Attempts = types.Int64Value(0)
+	data.Duration = types.Int64Value(0)
+	data.LastError = types.StringValue("")
+	data.Output = types.DynamicNull()
 
 	window := helpers.RetryWindow{
 		Context:              ctx,
 		Timeout:              time.Duration(data.Timeout.ValueInt64()) * time.Millisecond,
 		Interval:             time.Duration(data.Interval.ValueInt64()) * time.Millisecond,
 		ConsecutiveSuccesses: int(data.ConsecutiveSuccesses.ValueInt64()),
+		MaxAttempts:          data.maxAttempts,
 	}
 
 	envMap := make(map[string]string)
@@ -216,6 +616,30 @@ func (r *LocalCommandResource) RunCommand(ctx context.Context, data *LocalComman
 		}
 
 	}
+	var secrets []string
+	if !data.SecretEnv.IsNull() {
+		secretEnv := make(map[string]string)
+		diag.Append(data.SecretEnv.ElementsAs(ctx, &secretEnv, false)...)
+		if diag.HasError() {
+			return
+		}
+		for k, v := range secretEnv {
+			envMap[k] = v
+			secrets = append(secrets, v)
+		}
+	}
+	var redactPatterns []string
+	if !data.RedactPatterns.IsNull() {
+		diag.Append(data.RedactPatterns.ElementsAs(ctx, &redactPatterns, false)...)
+		if diag.HasError() {
+			return
+		}
+	}
+	redact, err := newRedactor(redactPatterns, secrets)
+	if err != nil {
+		diag.AddAttributeError(tfpath.Root("redact_patterns"), "Invalid regex", fmt.Sprintf("Could not compile redact pattern: %v", err))
+		return
+	}
 
 	if data.CreateFile != nil {
 		abs, err := filepath.Abs(data.CreateFile.Path.ValueString())
@@ -226,47 +650,166 @@ func (r *LocalCommandResource) RunCommand(ctx context.Context, data *LocalComman
 		}
 		envMap["CHECKMATE_FILEPATH"] = abs
 	}
+	if !data.Files.IsNull() {
+		files := make(map[string]FileModel)
+		diag.Append(data.Files.ElementsAs(ctx, &files, false)...)
+		if diag.HasError() {
+			return
+		}
+		envMap["CHECKMATE_DIR"] = data.FilesDirectory.ValueString()
+		for k, f := range files {
+			envMap[fileEnvName(k)] = filepath.Join(data.FilesDirectory.ValueString(), f.RelativePath(k))
+		}
+	}
+
+	var stdoutRegex, stderrRegex *regexp.Regexp
+	if data.StdoutRegex.ValueString() != "" {
+		re, err := regexp.Compile(data.StdoutRegex.ValueString())
+		if err != nil {
+			diag.AddAttributeError(tfpath.Root("stdout_regex"), "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.StdoutRegex.ValueString(), err.Error()))
+			return
+		}
+		stdoutRegex = re
+	}
+	if data.StderrRegex.ValueString() != "" {
+		re, err := regexp.Compile(data.StderrRegex.ValueString())
+		if err != nil {
+			diag.AddAttributeError(tfpath.Root("stderr_regex"), "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.StderrRegex.ValueString(), err.Error()))
+			return
+		}
+		stderrRegex = re
+	}
+	var outputJSONPath *jsonpath.JSONPath
+	var outputValue *regexp.Regexp
+	if data.OutputJSONPath.ValueString() != "" {
+		outputJSONPath = jsonpath.New("parser")
+		if err := outputJSONPath.Parse(data.OutputJSONPath.ValueString()); err != nil {
+			diag.AddAttributeError(tfpath.Root("output_jsonpath"), "Invalid JSONPath expression", fmt.Sprintf("Could not parse JSONPath expression %q: %v", data.OutputJSONPath.ValueString(), err.Error()))
+			return
+		}
+		re, err := regexp.Compile(data.OutputValue.ValueString())
+		if err != nil {
+			diag.AddAttributeError(tfpath.Root("output_value"), "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.OutputValue.ValueString(), err.Error()))
+			return
+		}
+		outputValue = re
+	}
+	if err := healthcheck.ValidateStatusCodePattern(data.ExpectedExitCodes.ValueString()); err != nil {
+		diag.AddAttributeError(tfpath.Root("expected_exit_codes"), "Invalid exit code pattern", err.Error())
+		return
+	}
+
+	var allowlist []string
+	if !data.EnvAllowlist.IsNull() {
+		diag.Append(data.EnvAllowlist.ElementsAs(ctx, &allowlist, false)...)
+		if diag.HasError() {
+			return
+		}
+	}
+	env := commandEnv(os.Environ(), data.InheritEnv.ValueBool(), allowlist, envMap)
 
-	env := make([]string, 0, len(envMap))
-	for k, v := range envMap {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	argv := commandArgv(ctx, data, diag)
+	if diag.HasError() {
+		return
 	}
 
-	tflog.Debug(ctx, fmt.Sprintf("Command string: sh -c %s", data.Command.ValueString()))
-	result := window.Do(func(attempt int, successes int) bool {
+	tflog.Debug(ctx, redact.Redact(fmt.Sprintf("Command: %q", argv)))
+	logOutput := func(stdout, stderr string) {
+		if data.SensitiveOutput.ValueBool() {
+			return
+		}
+		tflog.Warn(ctx, redact.Redact(fmt.Sprintf("Command: %q", argv)))
+		tflog.Warn(ctx, fmt.Sprintf("Command stdout: %s", redact.Redact(stdout)))
+		tflog.Warn(ctx, fmt.Sprintf("Command stderr: %s", redact.Redact(stderr)))
+	}
+	var failure *commandError
+	result := window.DoContext(func(attemptCtx context.Context, attempt int, successes int) bool {
 		var stdout bytes.Buffer
 		var stderr bytes.Buffer
 
-		commandContext, cancelFunc := context.WithTimeout(ctx, time.Duration(data.CommandTimeout.ValueInt64())*time.Millisecond)
+		commandContext, cancelFunc := context.WithTimeout(attemptCtx, time.Duration(data.CommandTimeout.ValueInt64())*time.Millisecond)
 		defer cancelFunc()
 
-		cmd := exec.CommandContext(commandContext, "sh", "-c", data.Command.ValueString())
+		cmd := exec.CommandContext(commandContext, argv[0], argv[1:]...)
+		stopProcessGroup := setProcessGroup(cmd, time.Duration(data.GracePeriod.ValueInt64())*time.Millisecond)
 		cmd.Dir = data.WorkDir.ValueString()
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
-		cmd.Env = append(os.Environ(), env...)
+		var stdoutLogger, stderrLogger *lineLogger
+		if logf := outputLogFunc(data.OutputLogLevel.ValueString()); logf != nil && !data.SensitiveOutput.ValueBool() {
+			stdoutLogger = newLineLogger(func(line string) {
+				logf(ctx, fmt.Sprintf("ATTEMPT #%d stdout: %s", attempt, redact.Redact(line)))
+			})
+			stderrLogger = newLineLogger(func(line string) {
+				logf(ctx, fmt.Sprintf("ATTEMPT #%d stderr: %s", attempt, redact.Redact(line)))
+			})
+			cmd.Stdout = io.MultiWriter(&stdout, stdoutLogger)
+			cmd.Stderr = io.MultiWriter(&stderr, stderrLogger)
+		}
+		cmd.Env = env
+
+		data.Attempts = types.Int64Value(int64(attempt))
+		data.ExitCode = types.Int64Value(-1)
+		start := time.Now()
+		defer func() {
+			data.Duration = types.Int64Value(time.Since(start).Milliseconds())
+		}()
+
+		if !data.Stdin.IsNull() {
+			cmd.Stdin = strings.NewReader(data.Stdin.ValueString())
+		} else if !data.StdinFile.IsNull() {
+			f, err := os.Open(data.StdinFile.ValueString())
+			if err != nil {
+				tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d error opening stdin_file %v", attempt, err))
+				failure = &commandError{summary: "Could not open stdin_file", message: fmt.Sprintf("open stdin_file: %v", err)}
+				data.LastError = types.StringValue(failure.message)
+				return false
+			}
+			defer f.Close()
+			cmd.Stdin = f
+		}
 
 		err := cmd.Start()
-		if err != nil {
-			tflog.Trace(ctx, fmt.Sprintf("ATTEMPT #%d error starting command", attempt))
-			tflog.Error(ctx, fmt.Sprintf("Error starting command %v", err))
+		if err == nil {
+			err = cmd.Wait()
+			stopProcessGroup()
+			if stdoutLogger != nil {
+				stdoutLogger.Flush()
+				stderrLogger.Flush()
+			}
+			storeOutput(data, redact, stdout.String(), stderr.String())
+		}
+		exitCode, cmdErr := classifyCommandError(err, cmd.ProcessState, commandContext.Err() != nil)
+		data.ExitCode = types.Int64Value(int64(exitCode))
+		if cmdErr == nil {
+			cmdErr = exitCodeError(data.ExpectedExitCodes.ValueString(), exitCode)
+		}
+		if cmdErr != nil {
+			failure = &commandError{summary: cmdErr.summary, message: redact.Redact(cmdErr.message)}
+			tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d %s: %s", attempt, failure.summary, failure.message))
+			logOutput(stdout.String(), stderr.String())
+			data.LastError = types.StringValue(failure.message)
 			return false
 		}
-		err = cmd.Wait()
-		if err != nil {
-			tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d exit_code=%d err=%s", attempt, err.(*exec.ExitError).ExitCode(), err.Error()))
-			data.Stdout = types.StringValue(stdout.String())
-			data.Stderr = types.StringValue(stderr.String())
-			tflog.Warn(ctx, fmt.Sprintf("Command string: sh -c %s", data.Command.ValueString()))
-			tflog.Warn(ctx, fmt.Sprintf("Command stdout: %s", stdout.String()))
-			tflog.Warn(ctx, fmt.Sprintf("Command stderr: %s", stderr.String()))
+		if err := checkCommandOutput(data, stdout.String(), stderr.String(), stdoutRegex, stderrRegex); err != nil {
+			failure = &commandError{summary: "Unexpected command output", message: redact.Redact(err.Error())}
+			tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d exit_code=%d err=%s", attempt, exitCode, failure.message))
+			logOutput(stdout.String(), stderr.String())
+			data.LastError = types.StringValue(failure.message)
 			return false
 		}
+		if data.OutputFormat.ValueString() != "" {
+			if err := r.checkParsedOutput(ctx, data, stdout.String(), outputJSONPath, outputValue, redact); err != nil {
+				failure = &commandError{summary: "Unexpected command output", message: redact.Redact(err.Error())}
+				tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d err=%s", attempt, failure.message))
+				logOutput(stdout.String(), stderr.String())
+				data.LastError = types.StringValue(failure.message)
+				return false
+			}
+		}
+		failure = nil
+		data.LastError = types.StringValue("")
 		tflog.Trace(ctx, fmt.Sprintf("SUCCESS [%d/%d]", successes, data.ConsecutiveSuccesses.ValueInt64()))
-		data.Stdout = types.StringValue(stdout.String())
-		data.Stderr = types.StringValue(stderr.String())
-		tflog.Debug(ctx, fmt.Sprintf("Command stdout: %s", stdout.String()))
-		tflog.Debug(ctx, fmt.Sprintf("Command stdout: %s", stderr.String()))
 		return true
 	})
 
@@ -275,6 +818,9 @@ func (r *LocalCommandResource) RunCommand(ctx context.Context, data *LocalComman
 		data.Passed = types.BoolValue(true)
 	case helpers.TimeoutExceeded:
 		diag.AddWarning("Timeout exceeded", fmt.Sprintf("Timeout of %d milliseconds exceeded", data.Timeout.ValueInt64()))
+		if failure != nil {
+			diag.AddWarning(failure.summary, fmt.Sprintf("The last attempt failed: %s", failure.message))
+		}
 		if !data.IgnoreFailure.ValueBool() {
 			diag.AddError("Check failed", "The check did not pass and create_anyway_on_check_failure is false")
 			return
@@ -283,14 +829,161 @@ func (r *LocalCommandResource) RunCommand(ctx context.Context, data *LocalComman
 
 }
 
+// defaultInterpreter runs command when no interpreter is configured
+var defaultInterpreter = []string{"sh", "-c"}
+
+// commandArgv returns the program to run followed by its arguments, either
+// args or command passed to the interpreter.
+func commandArgv(ctx context.Context, data *LocalCommandResourceModel, diag *diag.Diagnostics) []string {
+	var argv []string
+	if !data.Args.IsNull() {
+		diag.Append(data.Args.ElementsAs(ctx, &argv, false)...)
+		return argv
+	}
+	interpreter := defaultInterpreter
+	if !data.Interpreter.IsNull() {
+		interpreter = nil
+		diag.Append(data.Interpreter.ElementsAs(ctx, &interpreter, false)...)
+	}
+	argv = append(argv, interpreter...)
+	return append(argv, data.Command.ValueString())
+}
+
+// storeOutput stores the redacted output of an attempt in the stdout and
+// stderr attributes, or their sensitive versions, unless it's omitted.
+func storeOutput(data *LocalCommandResourceModel, redact *redactor, stdout, stderr string) {
+	if data.OmitOutput.ValueBool() {
+		return
+	}
+	stored := func(output string) types.String {
+		return types.StringValue(tailOutput(redact.Redact(output), data.MaxOutputBytes.ValueInt64()))
+	}
+	if data.SensitiveOutput.ValueBool() {
+		data.SensitiveStdout = stored(stdout)
+		data.SensitiveStderr = stored(stderr)
+		return
+	}
+	data.Stdout = stored(stdout)
+	data.Stderr = stored(stderr)
+}
+
+// checkCommandOutput returns an error describing why the output of an attempt
+// doesn't satisfy the configured expectations, if they don't.
+func checkCommandOutput(data *LocalCommandResourceModel, stdout, stderr string, stdoutRegex, stderrRegex *regexp.Regexp) error {
+	if data.FailOnStderr.ValueBool() && stderr != "" {
+		return errors.New("stderr is not empty")
+	}
+	if stdoutRegex != nil && !stdoutRegex.MatchString(stdout) {
+		return fmt.Errorf("stdout does not match regex %q", stdoutRegex.String())
+	}
+	if stderrRegex != nil && !stderrRegex.MatchString(stderr) {
+		return fmt.Errorf("stderr does not match regex %q", stderrRegex.String())
+	}
+	if data.StdoutContains.ValueString() != "" && !strings.Contains(stdout, data.StdoutContains.ValueString()) {
+		return fmt.Errorf("stdout does not contain %q", data.StdoutContains.ValueString())
+	}
+	return nil
+}
+
+// checkParsedOutput parses stdout into the output attribute, unless the output
+// is sensitive, and applies the JSONPath assertion if there's one.
+func (r *LocalCommandResource) checkParsedOutput(ctx context.Context, data *LocalCommandResourceModel, stdout string, jp *jsonpath.JSONPath, re *regexp.Regexp, redact *redactor) error {
+	sensitive := data.SensitiveOutput.ValueBool()
+	data.Output = types.DynamicNull()
+	parsed, err := parseCommandOutput(data.OutputFormat.ValueString(), stdout)
+	if err != nil {
+		return outputError(sensitive, fmt.Sprintf("parse %s output", data.OutputFormat.ValueString()), err)
+	}
+	value, err := dynamicValue(ctx, redact.RedactValue(parsed))
+	if err != nil {
+		return outputError(sensitive, fmt.Sprintf("convert %s output", data.OutputFormat.ValueString()), err)
+	}
+	if !sensitive {
+		data.Output = types.DynamicValue(value)
+	}
+
+	if jp == nil {
+		return nil
+	}
+	ok, result, err := matchJSONPath(jp, re, parsed)
+	if err != nil {
+		return outputError(sensitive, fmt.Sprintf("execute JSONPath %q", data.OutputJSONPath.ValueString()), err)
+	}
+	if !ok {
+		if sensitive {
+			return fmt.Errorf("JSONPath %q result does not match %q", data.OutputJSONPath.ValueString(), data.OutputValue.ValueString())
+		}
+		return fmt.Errorf("JSONPath %q result %q does not match %q", data.OutputJSONPath.ValueString(), result, data.OutputValue.ValueString())
+	}
+	return nil
+}
+
+// outputError wraps an error about the output of the command. The error can
+// quote the output, so it's left out if the output is sensitive, as the
+// message ends up in last_error and the logs.
+func outputError(sensitive bool, msg string, err error) error {
+	if sensitive {
+		return fmt.Errorf("%s failed, details omitted because sensitive_output is true", msg)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
 // Delete implements resource.Resource
-func (*LocalCommandResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+func (r *LocalCommandResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
 	var data *LocalCommandResourceModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	r.RunDestroyCommand(ctx, data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.RemoveFile(ctx, data.CreateFile, &resp.Diagnostics)
+	r.RemoveFiles(ctx, data.FilesDirectory.ValueString(), &resp.Diagnostics)
+}
+
+// RunDestroyCommand runs the destroy command like the check, with its own
+// command and retry settings and without the assertions on the output.
+func (r *LocalCommandResource) RunDestroyCommand(ctx context.Context, data *LocalCommandResourceModel, diag *diag.Diagnostics) {
+	d := data.Destroy
+	if d == nil {
+		return
+	}
+
+	destroy := *data
+	destroy.Command = d.Command
+	destroy.Args = types.ListNull(types.StringType)
+	destroy.Timeout = d.Timeout
+	destroy.CommandTimeout = d.CommandTimeout
+	destroy.Interval = d.Interval
+	destroy.ConsecutiveSuccesses = d.ConsecutiveSuccesses
+	destroy.Stdin = types.StringNull()
+	destroy.StdinFile = types.StringNull()
+	destroy.ExpectedExitCodes = types.StringValue("0")
+	destroy.StdoutRegex = types.StringNull()
+	destroy.StderrRegex = types.StringNull()
+	destroy.StdoutContains = types.StringNull()
+	destroy.FailOnStderr = types.BoolValue(false)
+	destroy.OutputFormat = types.StringNull()
+	destroy.OutputJSONPath = types.StringNull()
+	destroy.OutputValue = types.StringNull()
+	// failures are reported below, with a message about ignore_failure
+	destroy.IgnoreFailure = types.BoolValue(true)
+
+	tflog.Debug(ctx, "Running destroy command")
+	r.RunCommand(ctx, &destroy, diag)
+	if diag.HasError() || destroy.Passed.ValueBool() {
+		return
+	}
+	if d.IgnoreFailure.ValueBool() {
+		diag.AddWarning("Destroy command failed", fmt.Sprintf("The destroy command did not succeed, but ignore_failure is true. Last error: %s", destroy.LastError.ValueString()))
+		return
+	}
+	diag.AddError("Destroy command failed", fmt.Sprintf("The destroy command did not succeed and ignore_failure is false. Last error: %s", destroy.LastError.ValueString()))
 }
 
 // Metadata implements resource.Resource
@@ -299,7 +992,7 @@ func (*LocalCommandResource) Metadata(ctx context.Context, req resource.Metadata
 }
 
 // Read implements resource.Resource
-func (*LocalCommandResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+func (r *LocalCommandResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
 	var data *LocalCommandResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -307,14 +1000,44 @@ func (*LocalCommandResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
+	if cf := data.CreateFile; cf != nil && cf.Path.ValueString() != "" {
+		if _, err := os.Stat(cf.Path.ValueString()); errors.Is(err, os.ErrNotExist) {
+			tflog.Info(ctx, fmt.Sprintf("File %q was removed, the resource will be created again", cf.Path.ValueString()))
+			resp.State.RemoveResource(ctx)
+			return
+		}
+	}
+	if dir := data.FilesDirectory.ValueString(); dir != "" {
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			tflog.Info(ctx, fmt.Sprintf("Files directory %q was removed, the resource will be created again", dir))
+			resp.State.RemoveResource(ctx)
+			return
+		}
+	}
+
+	if check := prepareRecheck(data); check != nil {
+		r.RunCommand(ctx, check, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Passed = check.Passed
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// ModifyPlan implements resource.ResourceWithModifyPlan
+func (*LocalCommandResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	planRecheckReplacement(ctx, req, resp)
+}
+
 // Update implements resource.Resource
 func (r *LocalCommandResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var data *LocalCommandResourceModel
+	var state *LocalCommandResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -324,10 +1047,23 @@ func (r *LocalCommandResource) Update(ctx context.Context, req resource.UpdateRe
 		return
 	}
 
+	r.EnsureFiles(ctx, data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		r.DiscardFile(ctx, data.CreateFile, state.CreateFile, &resp.Diagnostics)
+		return
+	}
+
 	r.RunCommand(ctx, data, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
+		// the state keeps the old file and directory
+		r.DiscardFile(ctx, data.CreateFile, state.CreateFile, &resp.Diagnostics)
+		r.RemoveFiles(ctx, data.FilesDirectory.ValueString(), &resp.Diagnostics)
 		return
 	}
+	if old := state.CreateFile; old != nil && (data.CreateFile == nil || !old.Path.Equal(data.CreateFile.Path)) {
+		r.RemoveFile(ctx, old, &resp.Diagnostics)
+	}
+	r.RemoveFiles(ctx, state.FilesDirectory.ValueString(), &resp.Diagnostics)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -342,29 +1078,47 @@ func (r *LocalCommandResource) EnsureFile(ctx context.Context, data *LocalComman
 		return
 	}
 
-	var file *os.File
-	var err error
+	mode, err := parseFileMode(cf.Mode.ValueString())
+	if err != nil {
+		diag.AddAttributeError(tfpath.Root("create_file").AtName("mode"), "Invalid file mode", fmt.Sprintf("Could not parse file mode %q: %v", cf.Mode.ValueString(), err))
+		return
+	}
+
+	dir := ""
 	if cf.UseWorkingDirectory.ValueBool() {
+		dir = data.WorkDir.ValueString()
 		if cf.CreateDirectory.ValueBool() {
-			dir := filepath.Join(data.WorkDir.ValueString(), filepath.Dir(cf.Name.ValueString()))
-			err = os.MkdirAll(dir, 0750)
+			target := filepath.Join(dir, filepath.Dir(cf.Name.ValueString()))
+			err := os.MkdirAll(target, 0750)
 			if err != nil {
-				diag.AddError("Error creating directory", fmt.Sprintf("Error creating directory %q. %v", dir, err))
+				diag.AddError("Error creating directory", fmt.Sprintf("Error creating directory %q. %v", target, err))
 				return
 			}
 
 		}
-		file, err = os.CreateTemp(data.WorkDir.ValueString(), cf.Name.ValueString())
-		if err != nil {
-			diag.AddError("Error creating file", fmt.Sprintf("%s", err))
-			return
+	}
+
+	var file *os.File
+	if cf.ExactName.ValueBool() {
+		if dir == "" {
+			dir = os.TempDir()
 		}
+		file, err = os.OpenFile(filepath.Join(dir, cf.Name.ValueString()), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
 	} else {
-		file, err = os.CreateTemp("", cf.Name.ValueString())
-		if err != nil {
-			diag.AddError("Error creating file", fmt.Sprintf("%s", err))
-			return
-		}
+		file, err = os.CreateTemp(dir, cf.Name.ValueString())
+	}
+	if err != nil {
+		diag.AddError("Error creating file", fmt.Sprintf("%s", err))
+		return
+	}
+	defer file.Close()
+
+	// the mode of an existing file isn't changed by OpenFile, and the umask
+	// applies to new ones
+	err = file.Chmod(mode)
+	if err != nil {
+		diag.AddError("Error setting file mode", fmt.Sprintf("%s", err))
+		return
 	}
 
 	_, err = file.WriteString(cf.Contents.ValueString())
@@ -375,3 +1129,71 @@ func (r *LocalCommandResource) EnsureFile(ctx context.Context, data *LocalComman
 
 	cf.Path = types.StringValue(file.Name())
 }
+
+// RemoveFile deletes the file created for cf, unless it should be kept.
+func (r *LocalCommandResource) RemoveFile(ctx context.Context, cf *CreateFileModel, diag *diag.Diagnostics) {
+	if cf == nil || cf.Path.ValueString() == "" || !cf.DeleteOnDestroy.ValueBool() {
+		return
+	}
+	removeFile(ctx, cf.Path.ValueString(), diag)
+}
+
+// DiscardFile deletes the file created for cf by a create or update whose check
+// failed, as it isn't stored in the state. The file is kept if it's the same
+// as the one in the state, old.
+func (r *LocalCommandResource) DiscardFile(ctx context.Context, cf, old *CreateFileModel, diag *diag.Diagnostics) {
+	if cf == nil || cf.Path.ValueString() == "" {
+		return
+	}
+	if old != nil && old.Path.Equal(cf.Path) {
+		return
+	}
+	removeFile(ctx, cf.Path.ValueString(), diag)
+}
+
+func removeFile(ctx context.Context, path string, diag *diag.Diagnostics) {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		diag.AddWarning("Error deleting file", fmt.Sprintf("Could not delete %q: %v", path, err))
+		return
+	}
+	tflog.Debug(ctx, fmt.Sprintf("Deleted file %q", path))
+}
+
+// EnsureFiles writes the files in the files attribute into a new temporary
+// directory.
+func (r *LocalCommandResource) EnsureFiles(ctx context.Context, data *LocalCommandResourceModel, diag *diag.Diagnostics) {
+	data.FilesDirectory = types.StringNull()
+	if data.Files.IsNull() {
+		return
+	}
+	files := make(map[string]FileModel)
+	diag.Append(data.Files.ElementsAs(ctx, &files, false)...)
+	if diag.HasError() {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "checkmate-")
+	if err != nil {
+		diag.AddError("Error creating directory", fmt.Sprintf("Error creating files directory. %v", err))
+		return
+	}
+	if err := writeFiles(dir, files); err != nil {
+		os.RemoveAll(dir)
+		diag.AddAttributeError(tfpath.Root("files"), "Error writing file", err.Error())
+		return
+	}
+	data.FilesDirectory = types.StringValue(dir)
+}
+
+// RemoveFiles deletes the directory created for the files attribute.
+func (r *LocalCommandResource) RemoveFiles(ctx context.Context, dir string, diag *diag.Diagnostics) {
+	if dir == "" {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		diag.AddWarning("Error deleting directory", fmt.Sprintf("Could not delete %q: %v", dir, err))
+		return
+	}
+	tflog.Debug(ctx, fmt.Sprintf("Deleted directory %q", dir))
+}
@@ -16,9 +16,13 @@ package provider
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
 func TestAccLocalCommandResource(t *testing.T) {
@@ -49,6 +53,602 @@ func TestAccLocalCommandResource(t *testing.T) {
 	})
 }
 
+func TestAccLocalCommandResourceCreateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "check.sh")
+	config := fmt.Sprintf(`
+resource "checkmate_local_command" "test_create_file" {
+	command = "sh $CHECKMATE_FILEPATH"
+	working_directory = %q
+	timeout = 1000
+	create_file = {
+		name = "check.sh"
+		contents = "echo hello"
+		exact_name = true
+		use_working_dir = true
+		mode = "0750"
+	}
+}`, dir)
+	checkFile := func(*terraform.State) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm() != 0750 {
+			return fmt.Errorf("expected mode 0750, got %v", info.Mode().Perm())
+		}
+		return nil
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				return fmt.Errorf("expected %q to be deleted, got %v", path, err)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_create_file", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_create_file", "stdout", "hello\n"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_create_file", "create_file.path", path),
+					checkFile,
+				),
+			},
+			{
+				// the file is created again if it's removed
+				PreConfig: func() {
+					if err := os.Remove(path); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_create_file", "passed", "true"),
+					checkFile,
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceCreateFileUpdateFailure(t *testing.T) {
+	dir := t.TempDir()
+	config := func(command string) string {
+		return fmt.Sprintf(`
+resource "checkmate_local_command" "test_create_file_update" {
+	command = %q
+	working_directory = %q
+	timeout = 500
+	create_file = {
+		name = "check-*.sh"
+		contents = "echo hello"
+		use_working_dir = true
+	}
+}`, command, dir)
+	}
+	var path string
+	// the file in the state is kept and the one created for the failed update
+	// is removed
+	checkFiles := func(*terraform.State) error {
+		files, err := filepath.Glob(filepath.Join(dir, "check-*.sh"))
+		if err != nil {
+			return err
+		}
+		if len(files) != 1 || files[0] != path {
+			return fmt.Errorf("expected only %q, got %q", path, files)
+		}
+		return nil
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("sh $CHECKMATE_FILEPATH"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrWith("checkmate_local_command.test_create_file_update", "create_file.path", func(value string) error {
+						path = value
+						return nil
+					}),
+					checkFiles,
+				),
+			},
+			{
+				Config:      config("sh $CHECKMATE_FILEPATH && false"),
+				ExpectError: regexp.MustCompile("Check failed"),
+			},
+			{
+				Config: config("sh $CHECKMATE_FILEPATH"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrWith("checkmate_local_command.test_create_file_update", "create_file.path", func(value string) error {
+						if value != path {
+							return fmt.Errorf("expected the resource to keep %q, got %q", path, value)
+						}
+						return nil
+					}),
+					checkFiles,
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceFiles(t *testing.T) {
+	var dir string
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				return fmt.Errorf("expected %q to be deleted, got %v", dir, err)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccLocalCommandResourceExtraConfig("test_files_path", "true", `files = { escape = { path = "../escape", contents = "" } }`),
+				ExpectError: regexp.MustCompile("must be a relative path inside the files directory"),
+			},
+			{
+				Config:      testAccLocalCommandResourceExtraConfig("test_files_case", "true", `files = { ca = { contents = "" }, CA = { contents = "" } }`),
+				ExpectError: regexp.MustCompile(`"CA" and "ca" only differ in case`),
+			},
+			{
+				Config:      testAccLocalCommandResourceExtraConfig("test_files_base64", "true", `files = { ca = { contents_base64 = "Y2VydA" } }`),
+				ExpectError: regexp.MustCompile("Invalid base64"),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_files", `cat $CHECKMATE_FILE_CA $CHECKMATE_DIR/script.sh && sh $CHECKMATE_FILE_SCRIPT`, `files = {
+		ca = {
+			path            = "certs/ca.pem"
+			contents_base64 = base64encode("cert\n")
+		}
+		script = {
+			path     = "script.sh"
+			contents = "echo hello"
+			mode     = "0755"
+		}
+	}`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_files", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_files", "stdout", "cert\necho hellohello\n"),
+					resource.TestCheckResourceAttrWith("checkmate_local_command.test_files", "files_directory", func(value string) error {
+						dir = value
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceFilesUpdateFailure(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	config := func(command string) string {
+		return fmt.Sprintf(`
+resource "checkmate_local_command" "test_files_update" {
+	command = %q
+	timeout = 500
+	files = { config = { contents = "x" } }
+}`, command)
+	}
+	// the directory in the state is kept and the one created for the failed
+	// update is removed
+	checkDirs := func(*terraform.State) error {
+		dirs, err := filepath.Glob(filepath.Join(tmp, "checkmate-*"))
+		if err != nil {
+			return err
+		}
+		if len(dirs) != 1 {
+			return fmt.Errorf("expected a single files directory, got %q", dirs)
+		}
+		return nil
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("test -f $CHECKMATE_FILE_CONFIG"),
+				Check:  checkDirs,
+			},
+			{
+				Config:      config("test -f $CHECKMATE_FILE_CONFIG && false"),
+				ExpectError: regexp.MustCompile("Check failed"),
+			},
+			{
+				Config: config("test -f $CHECKMATE_FILE_CONFIG"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					checkDirs,
+					resource.TestCheckResourceAttrWith("checkmate_local_command.test_files_update", "files_directory", func(value string) error {
+						_, err := os.Stat(value)
+						return err
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceDestroy(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "destroyed")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			data, err := os.ReadFile(marker)
+			if err != nil {
+				return fmt.Errorf("destroy command didn't run: %w", err)
+			}
+			if string(data) != "web\n" {
+				return fmt.Errorf("unexpected destroy command output %q", data)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				// the destroy command waits for the marker file to be created by
+				// an earlier attempt, and runs with the same environment
+				Config: testAccLocalCommandResourceExtraConfig("test_destroy", "true", fmt.Sprintf(`env = { APP = "web" }
+	destroy = {
+		command = "test -f %[1]s.tmp && mv %[1]s.tmp %[1]s || (echo $APP > %[1]s.tmp; false)"
+		interval = 10
+	}`, marker)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_destroy", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_destroy", "destroy.timeout", "10000"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_destroy_failure", "true", `destroy = {
+		command = "false"
+		timeout = 100
+		ignore_failure = true
+	}`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_destroy_failure", "passed", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceRecheck(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "healthy")
+	if err := os.WriteFile(marker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	removeMarker := func() {
+		if err := os.Remove(marker); err != nil {
+			t.Fatal(err)
+		}
+	}
+	command := fmt.Sprintf("test -f %s", marker)
+	replaceConfig := fmt.Sprintf(`
+resource "checkmate_local_command" "test_recheck_replace" {
+	command = %q
+	timeout = 500
+	recheck_on_refresh = "window"
+}`, command)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_recheck", command, `recheck_on_refresh = "attempt"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_recheck", "passed", "true"),
+				),
+			},
+			{
+				// the check fails on refresh, so it's updated in the state
+				PreConfig:    removeMarker,
+				RefreshState: true,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_recheck", "passed", "false"),
+				),
+			},
+			{
+				PreConfig: func() {
+					if err := os.WriteFile(marker, nil, 0600); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: replaceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_recheck_replace", "passed", "true"),
+				),
+			},
+			{
+				// the resource is replaced, since create_anyway_on_check_failure is
+				// false
+				PreConfig:          removeMarker,
+				Config:             replaceConfig,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceAssertions(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_exit_code", "exit 3", `expected_exit_codes = "0,2-3"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit_code", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit_code", "exit_code", "3"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit_code", "attempts", "1"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit_code", "last_error", ""),
+					resource.TestCheckResourceAttrSet("checkmate_local_command.test_exit_code", "duration_ms"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_stdout_regex", "echo pod/web-1", `stdout_regex = "^pod/web-[0-9]+"
+	stdout_contains = "web"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdout_regex", "passed", "true"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_stdout_contains", "echo pod/web-1", `stdout_contains = "db"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdout_contains", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdout_contains", "last_error", `stdout does not contain "db"`),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_stderr_regex", "echo warning >&2", `stderr_regex = "warning"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stderr_regex", "passed", "true"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_fail_on_stderr", "echo warning >&2", `fail_on_stderr = true`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_fail_on_stderr", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_fail_on_stderr", "stderr", "warning\n"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_exit", "exit 3", ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit", "exit_code", "3"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_exit", "last_error", "exit 3"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_killed", "sleep 5", "command_timeout = 100"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_killed", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_killed", "exit_code", "-1"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_killed", "last_error", "killed by timeout"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceInterpreter(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_interpreter", "false; echo ran", `interpreter = ["sh", "-e", "-c"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_interpreter", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_interpreter", "stdout", ""),
+				),
+			},
+			{
+				Config: `
+resource "checkmate_local_command" "test_args" {
+	args = ["printf", "%s", "$HOME 'quoted'"]
+	timeout = 500
+}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_args", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_args", "stdout", "$HOME 'quoted'"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceStdin(t *testing.T) {
+	stdinFile := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(stdinFile, []byte("from file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_stdin", "cat", `stdin = "from stdin"
+	consecutive_successes = 2`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdin", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdin", "stdout", "from stdin"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_stdin_file", "cat", fmt.Sprintf("stdin_file = %q", stdinFile)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdin_file", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_stdin_file", "stdout", "from file"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceOutputLimits(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_max_output", "echo 0123456789; echo abcdef >&2", `max_output_bytes = 4
+	output_log_level = "info"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_max_output", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_max_output", "stdout", "789\n"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_max_output", "stderr", "def\n"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_omit_output", "echo ready", `omit_output = true
+	stdout_regex = "^ready"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_omit_output", "passed", "true"),
+					resource.TestCheckNoResourceAttr("checkmate_local_command.test_omit_output", "stdout"),
+					resource.TestCheckNoResourceAttr("checkmate_local_command.test_omit_output", "stderr"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceRedaction(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_redact", `echo "user=$USERNAME password=$PASSWORD"; echo "token: abc123" >&2`, `env = { USERNAME = "admin" }
+	secret_env = { PASSWORD = "hunter2" }
+	redact_patterns = ["token: [a-z0-9]+"]
+	stdout_contains = "hunter2"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_redact", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_redact", "stdout", "user=admin password=[REDACTED]\n"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_redact", "stderr", "[REDACTED]\n"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_sensitive", `echo '{"token": "abc123"}'`, `sensitive_output = true
+	output_format = "json"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_sensitive", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_sensitive", "sensitive_stdout", "{\"token\": \"abc123\"}\n"),
+					resource.TestCheckNoResourceAttr("checkmate_local_command.test_sensitive", "stdout"),
+					resource.TestCheckNoResourceAttr("checkmate_local_command.test_sensitive", "output"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_sensitive", `echo '{"token": "abc123"}'`, `sensitive_output = true
+	output_format = "json"
+	output_jsonpath = "{.token}"
+	output_value = "^xyz$"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_sensitive", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_sensitive", "last_error", `JSONPath "{.token}" result does not match "^xyz$"`),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_sensitive", `echo 'token abc123'`, `sensitive_output = true
+	output_format = "json"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_sensitive", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_sensitive", "last_error", "parse json output failed, details omitted because sensitive_output is true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceInheritEnv(t *testing.T) {
+	t.Setenv("CHECKMATE_TEST_SECRET", "secret")
+	t.Setenv("CHECKMATE_TEST_ALLOWED", "allowed")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccLocalCommandResourceExtraConfig("test_env_allowlist", "true", `env_allowlist = ["HOME"]`),
+				ExpectError: regexp.MustCompile("env_allowlist only applies when inherit_env is false"),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_inherit_env", `echo "$${CHECKMATE_TEST_SECRET:-unset} $CHECKMATE_TEST_ALLOWED $APP"`, ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_inherit_env", "stdout", "secret allowed \n"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_inherit_env", `echo "$${CHECKMATE_TEST_SECRET:-unset} $CHECKMATE_TEST_ALLOWED $APP"`, `inherit_env = false
+	env_allowlist = ["CHECKMATE_TEST_ALLOW*"]
+	env = { APP = "web" }`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_inherit_env", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_inherit_env", "stdout", "unset allowed web\n"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccLocalCommandResourceOutput(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_json", `echo '{"status": {"phase": "Running"}, "replicas": 3, "owner": null, "tags": ["a", 1]}'`, `output_format = "json"
+	output_jsonpath = "{.status.phase}"
+	output_value = "^Running$"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_json", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_json", "output.status.phase", "Running"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_json", "output.replicas", "3"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_kv", `printf 'STATUS=ok
+VERSION=1.2
+'`, `output_format = "kv"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_kv", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_kv", "output.VERSION", "1.2"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_invalid", `echo 'Warning: deprecated'; echo '{}'`, `output_format = "json"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_invalid", "passed", "false"),
+				),
+			},
+			{
+				Config: testAccLocalCommandResourceExtraConfig("test_yaml_mismatch", `printf 'phase: Pending
+'`, `output_format = "yaml"
+	output_jsonpath = "{.phase}"
+	output_value = "^Running$"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_local_command.test_yaml_mismatch", "passed", "false"),
+					resource.TestCheckResourceAttr("checkmate_local_command.test_yaml_mismatch", "output.phase", "Pending"),
+				),
+			},
+		},
+	})
+}
+
 func testAccLocalCommandResourceConfig(name string, command string, ignore_failure bool) string {
 	return fmt.Sprintf(`
 resource "checkmate_local_command" %[1]q {
@@ -70,3 +670,13 @@ resource "checkmate_local_command" %[1]q {
 	}
 }`, name)
 }
+
+func testAccLocalCommandResourceExtraConfig(name, command, extra string) string {
+	return fmt.Sprintf(`
+resource "checkmate_local_command" %[1]q {
+	command = %[2]q
+	timeout = 500
+	create_anyway_on_check_failure = true
+	%[3]s
+}`, name, command, extra)
+}
@@ -17,15 +17,20 @@ package provider
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -40,10 +45,14 @@ import (
 
 	"github.com/tetratelabs/terraform-provider-checkmate/pkg/helpers"
 	"github.com/tetratelabs/terraform-provider-checkmate/pkg/modifiers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/tcpcheck"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/validators"
 )
 
 var _ resource.Resource = &TCPEchoResource{}
 var _ resource.ResourceWithImportState = &TCPEchoResource{}
+var _ resource.ResourceWithModifyPlan = &TCPEchoResource{}
+var _ resource.ResourceWithValidateConfig = &TCPEchoResource{}
 
 type TCPEchoResource struct{}
 
@@ -65,16 +74,99 @@ func (*TCPEchoResource) Schema(ctx context.Context, req resource.SchemaRequest,
 				},
 			},
 			"message": schema.StringAttribute{
-				MarkdownDescription: "The message to send in the echo request",
-				Required:            true,
+				MarkdownDescription: "The message to send in the echo request. Exactly one of `message`, `message_hex` or `message_base64` must be set",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.ExactlyOneOf(path.MatchRoot("message_hex"), path.MatchRoot("message_base64")),
+				},
+			},
+			"message_hex": schema.StringAttribute{
+				MarkdownDescription: "The message to send in the echo request, as hex encoded bytes. Whitespace is ignored",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(hexPayloadRegex, "must be hex encoded bytes"),
+				},
+			},
+			"message_base64": schema.StringAttribute{
+				MarkdownDescription: "The message to send in the echo request, as base64 encoded bytes",
+				Optional:            true,
+				Validators:          []validator.String{validators.Base64()},
+			},
+			"append_newline": schema.BoolAttribute{
+				MarkdownDescription: "Whether to append a newline to the message before sending it. Defaults to true.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
 			},
 			"expected_message": schema.StringAttribute{
-				MarkdownDescription: "The message expected to be included in the echo response",
+				MarkdownDescription: "The message expected to be included in the echo response. One of `expected_message`, `expected_hex` or `expected_base64` is required unless `expect_write_failure` is true",
 				Required:            false,
 				Optional:            true,
 				Computed:            true,
 				Default:             stringdefault.StaticString(""),
 			},
+			"expected_hex": schema.StringAttribute{
+				MarkdownDescription: "The bytes expected to be included in the echo response, hex encoded. Whitespace is ignored",
+				Optional:            true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(hexPayloadRegex, "must be hex encoded bytes"),
+					stringvalidator.ConflictsWith(path.MatchRoot("expected_message"), path.MatchRoot("expected_base64")),
+				},
+			},
+			"expected_base64": schema.StringAttribute{
+				MarkdownDescription: "The bytes expected to be included in the echo response, base64 encoded",
+				Optional:            true,
+				Validators: []validator.String{
+					validators.Base64(),
+					stringvalidator.ConflictsWith(path.MatchRoot("expected_message")),
+				},
+			},
+			"read_until": schema.StringAttribute{
+				MarkdownDescription: "How to read the response. `once` performs a single read, `delimiter` reads until `read_delimiter` is received, `bytes` reads exactly `read_bytes` bytes, `eof` reads until the server closes the connection and `idle` reads until no data is received for `read_idle_timeout` milliseconds. Default `once`",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString(tcpcheck.ReadOnce)},
+				Validators: []validator.String{
+					stringvalidator.OneOf(tcpcheck.ReadModes...),
+				},
+			},
+			"read_delimiter": schema.StringAttribute{
+				MarkdownDescription: "Delimiter that ends the response when `read_until` is `delimiter`",
+				Optional:            true,
+			},
+			"read_bytes": schema.Int64Attribute{
+				MarkdownDescription: "Number of bytes to read when `read_until` is `bytes`",
+				Optional:            true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"read_idle_timeout": schema.Int64Attribute{
+				MarkdownDescription: "Time in milliseconds without receiving data after which the response is considered complete when `read_until` is `idle`. Default 200",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(200)},
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"max_response_bytes": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of bytes to read from the response. Default 65536",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(65536)},
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"response": schema.StringAttribute{
+				MarkdownDescription: "Response received in the last attempt. Empty if the response is not valid UTF-8, in which case it is available in `response_base64`",
+				Computed:            true,
+			},
+			"response_base64": schema.StringAttribute{
+				MarkdownDescription: "Base64 encoded response received in the last attempt, only set when the response is not valid UTF-8",
+				Computed:            true,
+			},
 			"persistent_response_regex": schema.StringAttribute{
 				MarkdownDescription: `A regex pattern that the response need to match in every attempt to be considered successful.
   If not provided, the response is not checked.
@@ -82,10 +174,11 @@ func (*TCPEchoResource) Schema(ctx context.Context, req resource.SchemaRequest,
   If using multiple attempts, this regex will be evaulated against the response text. For every susequent attempt, the regex
   will be evaluated against the response text and compared against the first obtained value. The check will be deemed successful
   if the regex matches the response text in every attempt. A single response not matching such value will cause the check to fail.`,
-				Required: false,
-				Optional: true,
-				Computed: true,
-				Default:  stringdefault.StaticString(""),
+				Required:   false,
+				Optional:   true,
+				Computed:   true,
+				Default:    stringdefault.StaticString(""),
+				Validators: []validator.String{validators.Regex()},
 			},
 			"expect_write_failure": schema.BoolAttribute{
 				MarkdownDescription: "Wether or not the check is expected to fail after successfully connecting to the target. If true, the check will be considered successful if it fails. Defaults to false.",
@@ -94,6 +187,26 @@ func (*TCPEchoResource) Schema(ctx context.Context, req resource.SchemaRequest,
 				Computed:            true,
 				Default:             booldefault.StaticBool(false),
 			},
+			"all_addresses": schema.BoolAttribute{
+				MarkdownDescription: "If true, the message is sent to every address `host` resolves to concurrently and `quorum` decides whether the attempt passed. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"quorum": schema.StringAttribute{
+				MarkdownDescription: "How many addresses have to pass for an attempt to pass when `all_addresses` is true. Either `all`, `any` or a number. Default `all`",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString(helpers.QuorumAll)},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(helpers.QuorumRegex, "must be \"all\", \"any\" or a positive number"),
+				},
+			},
+			"address_results": schema.MapAttribute{
+				ElementType:         types.BoolType,
+				MarkdownDescription: "Whether each checked address passed in the last attempt, keyed by address",
+				Computed:            true,
+			},
 			"timeout": schema.Int64Attribute{
 				MarkdownDescription: "Overall timeout in milliseconds for the check before giving up, default 10000",
 				Optional:            true,
@@ -128,6 +241,7 @@ func (*TCPEchoResource) Schema(ctx context.Context, req resource.SchemaRequest,
 				Computed:            true,
 				MarkdownDescription: "True if the check passed",
 			},
+			"recheck_on_refresh": recheckOnRefreshAttribute(),
 			"create_anyway_on_check_failure": schema.BoolAttribute{
 				Optional:            true,
 				MarkdownDescription: "If false, the resource will fail to create if the check does not pass. If true, the resource will be created anyway. Defaults to false.",
@@ -150,9 +264,24 @@ type TCPEchoResourceModel struct {
 	Host                    types.String `tfsdk:"host"`
 	Port                    types.Int64  `tfsdk:"port"`
 	Message                 types.String `tfsdk:"message"`
+	MessageHex              types.String `tfsdk:"message_hex"`
+	MessageBase64           types.String `tfsdk:"message_base64"`
+	AppendNewline           types.Bool   `tfsdk:"append_newline"`
 	ExpectedMessage         types.String `tfsdk:"expected_message"`
+	ExpectedHex             types.String `tfsdk:"expected_hex"`
+	ExpectedBase64          types.String `tfsdk:"expected_base64"`
 	PersistentResponseRegex types.String `tfsdk:"persistent_response_regex"`
+	ReadUntil               types.String `tfsdk:"read_until"`
+	ReadDelimiter           types.String `tfsdk:"read_delimiter"`
+	ReadBytes               types.Int64  `tfsdk:"read_bytes"`
+	ReadIdleTimeout         types.Int64  `tfsdk:"read_idle_timeout"`
+	MaxResponseBytes        types.Int64  `tfsdk:"max_response_bytes"`
+	Response                types.String `tfsdk:"response"`
+	ResponseBase64          types.String `tfsdk:"response_base64"`
 	ExpectWriteFailure      types.Bool   `tfsdk:"expect_write_failure"`
+	AllAddresses            types.Bool   `tfsdk:"all_addresses"`
+	Quorum                  types.String `tfsdk:"quorum"`
+	AddressResults          types.Map    `tfsdk:"address_results"`
 	ConnectionTimeout       types.Int64  `tfsdk:"connection_timeout"`
 	SingleAttemptTimeout    types.Int64  `tfsdk:"single_attempt_timeout"`
 	Timeout                 types.Int64  `tfsdk:"timeout"`
@@ -160,7 +289,71 @@ type TCPEchoResourceModel struct {
 	ConsecutiveSuccesses    types.Int64  `tfsdk:"consecutive_successes"`
 	IgnoreFailure           types.Bool   `tfsdk:"create_anyway_on_check_failure"`
 	Passed                  types.Bool   `tfsdk:"passed"`
+	RecheckOnRefresh        types.String `tfsdk:"recheck_on_refresh"`
 	Keepers                 types.Map    `tfsdk:"keepers"`
+
+	// maxAttempts limits the attempts of the check if it's positive
+	maxAttempts int
+}
+
+// recheckFields implements recheckModel
+func (m *TCPEchoResourceModel) recheckFields() recheckFields {
+	return recheckFields{
+		mode:                 m.RecheckOnRefresh,
+		ignoreFailure:        &m.IgnoreFailure,
+		consecutiveSuccesses: &m.ConsecutiveSuccesses,
+		maxAttempts:          &m.maxAttempts,
+	}
+}
+
+// ValidateConfig implements resource.ResourceWithValidateConfig
+func (*TCPEchoResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data TCPEchoResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	expectedAttrs := []string{"expected_message", "expected_hex", "expected_base64"}
+	expectedValues := []types.String{data.ExpectedMessage, data.ExpectedHex, data.ExpectedBase64}
+	expectedSet := false
+	for _, v := range expectedValues {
+		// unknown values can't be checked until apply
+		if v.IsUnknown() || v.ValueString() != "" {
+			expectedSet = true
+		}
+	}
+	if !data.ExpectWriteFailure.IsUnknown() {
+		if data.ExpectWriteFailure.ValueBool() {
+			for i, v := range expectedValues {
+				if !v.IsUnknown() && v.ValueString() != "" {
+					resp.Diagnostics.AddAttributeError(path.Root(expectedAttrs[i]), "Conflicting configuration", fmt.Sprintf("%s can't be set when expect_write_failure is true, as no response is expected", expectedAttrs[i]))
+				}
+			}
+		} else if !expectedSet {
+			resp.Diagnostics.AddAttributeError(path.Root("expected_message"), "Missing expected message", "One of expected_message, expected_hex or expected_base64 is required when expect_write_failure is false")
+		}
+	}
+
+	if !data.ReadUntil.IsUnknown() {
+		readUntil := data.ReadUntil.ValueString()
+		if readUntil == "" {
+			readUntil = tcpcheck.ReadOnce
+		}
+		if readUntil == tcpcheck.ReadDelimiter && data.ReadDelimiter.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("read_delimiter"), "Missing read delimiter", "read_delimiter is required when read_until is \"delimiter\"")
+		}
+		if readUntil != tcpcheck.ReadDelimiter && !data.ReadDelimiter.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("read_delimiter"), "Conflicting configuration", fmt.Sprintf("read_delimiter is only used when read_until is \"delimiter\", got %q", readUntil))
+		}
+		if readUntil == tcpcheck.ReadBytes && data.ReadBytes.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("read_bytes"), "Missing read bytes", "read_bytes is required when read_until is \"bytes\"")
+		}
+		if readUntil != tcpcheck.ReadBytes && !data.ReadBytes.IsNull() {
+			resp.Diagnostics.AddAttributeError(path.Root("read_bytes"), "Conflicting configuration", fmt.Sprintf("read_bytes is only used when read_until is \"bytes\", got %q", readUntil))
+		}
+	}
 }
 
 // ImportState implements resource.ResourceWithImportState
@@ -188,105 +381,101 @@ func (r *TCPEchoResource) Create(ctx context.Context, req resource.CreateRequest
 }
 
 func (r *TCPEchoResource) TCPEcho(ctx context.Context, data *TCPEchoResourceModel, diag *diag.Diagnostics) {
-	if !data.ExpectWriteFailure.ValueBool() && data.ExpectedMessage.ValueString() == "" {
-		tflog.Error(ctx, "expected_message is required when expect_failure is false")
+	message, err := decodePayload(data.Message, data.MessageHex, data.MessageBase64)
+	if err != nil {
+		diag.AddError("Invalid message", err.Error())
+		return
+	}
+	if data.AppendNewline.ValueBool() {
+		message = append(message, '\n')
+	}
+	expected, err := decodePayload(data.ExpectedMessage, data.ExpectedHex, data.ExpectedBase64)
+	if err != nil {
+		diag.AddError("Invalid expected message", err.Error())
+		return
+	}
+
+	if !data.ExpectWriteFailure.ValueBool() && len(expected) == 0 {
+		diag.AddAttributeError(path.Root("expected_message"), "Missing expected message", "One of expected_message, expected_hex or expected_base64 is required when expect_write_failure is false")
 		return
 	}
-	if data.ExpectedMessage.ValueString() != "" && data.ExpectWriteFailure.ValueBool() {
-		tflog.Warn(ctx, "expected_message is ignored when expect_failure is true")
+
+	readOpts := tcpcheck.ReadOptions{
+		Until:       data.ReadUntil.ValueString(),
+		Delimiter:   []byte(data.ReadDelimiter.ValueString()),
+		Bytes:       int(data.ReadBytes.ValueInt64()),
+		IdleTimeout: time.Duration(data.ReadIdleTimeout.ValueInt64()) * time.Millisecond,
+		MaxBytes:    int(data.MaxResponseBytes.ValueInt64()),
+	}
+	if readOpts.Until == tcpcheck.ReadDelimiter && len(readOpts.Delimiter) == 0 {
+		diag.AddError("Invalid read configuration", "read_delimiter is required when read_until is \"delimiter\"")
+		return
+	}
+	if readOpts.Until == tcpcheck.ReadBytes && readOpts.Bytes == 0 {
+		diag.AddError("Invalid read configuration", "read_bytes is required when read_until is \"bytes\"")
+		return
 	}
 
 	data.Passed = types.BoolValue(false)
+	data.Response = types.StringValue("")
+	data.ResponseBase64 = types.StringValue("")
 
 	window := helpers.RetryWindow{
 		Context:              ctx,
 		Timeout:              time.Duration(data.Timeout.ValueInt64()) * time.Millisecond,
 		Interval:             time.Duration(data.Interval.ValueInt64()) * time.Millisecond,
 		ConsecutiveSuccesses: int(data.ConsecutiveSuccesses.ValueInt64()),
+		MaxAttempts:          data.maxAttempts,
 	}
 
-	previousRegexValue := ""
 	var persistentResponseRegex *regexp.Regexp
-	var err error
 	if data.PersistentResponseRegex.ValueString() != "" {
 		persistentResponseRegex, err = regexp.Compile(data.PersistentResponseRegex.ValueString())
 		if err != nil {
-			tflog.Error(ctx, fmt.Sprintf("could not compile regex %q: %v", data.PersistentResponseRegex.ValueString(), err.Error()))
-			diag.AddError("Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.PersistentResponseRegex.ValueString(), err.Error()))
+			diag.AddAttributeError(path.Root("persistent_response_regex"), "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.PersistentResponseRegex.ValueString(), err.Error()))
 			return
 		}
 	}
 
-	result := window.Do(func(attempt int, success int) bool {
-		exepctFailure := data.ExpectWriteFailure.ValueBool()
-		destStr := data.Host.ValueString() + ":" + strconv.Itoa(int(data.Port.ValueInt64()))
+	quorum, err := helpers.ParseQuorum(data.Quorum.ValueString())
+	if err != nil {
+		diag.AddAttributeError(path.Root("quorum"), "Invalid quorum", err.Error())
+		return
+	}
+	data.AddressResults = types.MapValueMust(types.BoolType, map[string]attr.Value{})
 
-		d := net.Dialer{Timeout: time.Duration(data.ConnectionTimeout.ValueInt64()) * time.Millisecond}
-		conn, err := d.Dial("tcp", destStr)
-		if err != nil {
-			tflog.Warn(ctx, fmt.Sprintf("dial %q failed: %v", destStr, err.Error()))
-			return false
-		}
-		defer conn.Close()
+	// previous values of the persistent regex, per address
+	previousRegexValues := map[string]string{}
 
-		_, err = conn.Write([]byte(data.Message.ValueString() + "\n"))
+	result := window.DoContext(func(attemptCtx context.Context, attempt int, success int) bool {
+		exepctFailure := data.ExpectWriteFailure.ValueBool()
+		addrs, err := addressesToCheck(attemptCtx, data.Host.ValueString(), data.AllAddresses.ValueBool())
 		if err != nil {
-			tflog.Warn(ctx, fmt.Sprintf("write to server failed: %v", err.Error()))
+			tflog.Warn(ctx, fmt.Sprintf("resolve %q failed: %v", data.Host.ValueString(), err.Error()))
 			return false
 		}
 
-		deadlineDuration := time.Millisecond * time.Duration(data.SingleAttemptTimeout.ValueInt64())
-		err = conn.SetDeadline(time.Now().Add(deadlineDuration))
-		if err != nil && !exepctFailure {
-			tflog.Warn(ctx, fmt.Sprintf("could not set connection deadline: %v", err.Error()))
-			return false
-		}
+		exchanges := helpers.FanOut(addrs, func(addr string) tcpEchoExchange {
+			return r.exchange(ctx, attemptCtx, data, addr, message, readOpts)
+		})
 
-		reply := make([]byte, 1024)
-		_, err = conn.Read(reply)
-		if err != nil {
-			if exepctFailure {
-				// We expected this
-				return true
+		passed := make([]bool, len(exchanges))
+		passedCount := 0
+		for i, ex := range exchanges {
+			passed[i] = r.evaluate(ctx, data, diag, ex, exepctFailure, expected, persistentResponseRegex, previousRegexValues, addrs[i])
+			if passed[i] {
+				passedCount++
 			}
-			tflog.Warn(ctx, fmt.Sprintf("read from server failed: %v", err.Error()))
-			return false
 		}
-		// At this point, if we expect failure, we can just return the check failed,
-		// as we were expecting it to fail
-		if exepctFailure {
-			return false
-		}
-
-		// remove null char from response
-		reply = bytes.Trim(reply, "\x00")
+		data.AddressResults = addressResultsValue(addrs, passed)
+		setResponse(data, exchanges[0].reply)
 
-		if persistentResponseRegex != nil {
-			limits := persistentResponseRegex.FindStringIndex(string(reply))
-			if limits == nil {
-				tflog.Warn(ctx, fmt.Sprintf("Got response %q, which does not match regex %q", string(reply), data.PersistentResponseRegex.ValueString()))
-				diag.AddWarning("Check failed", fmt.Sprintf("Got response %q, which does not match regex %q", string(reply), data.PersistentResponseRegex.ValueString()))
-				return false
+		if !quorum.Satisfied(passedCount, len(exchanges)) {
+			if len(exchanges) > 1 {
+				tflog.Warn(ctx, fmt.Sprintf("%d/%d addresses passed, quorum is %s", passedCount, len(exchanges), data.Quorum.ValueString()))
 			}
-			result := string(reply)[limits[0]:limits[1]]
-			// result := persistentResponseRegex.FindString(string(reply))
-			tflog.Info(ctx, fmt.Sprintf("Result: %s", result))
-
-			if previousRegexValue != result {
-				tflog.Warn(ctx, fmt.Sprintf("Got response %q, which does not match previous attempt %q", result, previousRegexValue))
-				diag.AddWarning("Check failed", fmt.Sprintf("Got response %q, which does not match previous attempt %q", result, previousRegexValue))
-
-				previousRegexValue = result
-				return false
-			}
-		}
-
-		if !strings.Contains(string(reply), data.ExpectedMessage.ValueString()) {
-			tflog.Warn(ctx, fmt.Sprintf("Got response %q, which does not include expected message %q", string(reply), data.ExpectedMessage.ValueString()))
-			diag.AddWarning("Check failed", fmt.Sprintf("Got response %q, which does not include expected message %q", string(reply), data.ExpectedMessage.ValueString()))
 			return false
 		}
-
 		return true
 	})
 
@@ -303,6 +492,95 @@ func (r *TCPEchoResource) TCPEcho(ctx context.Context, data *TCPEchoResourceMode
 
 }
 
+// tcpEchoExchange is the outcome of sending the message to a single address.
+type tcpEchoExchange struct {
+	// connected is false if the message could not be sent
+	connected bool
+	reply     []byte
+	readErr   error
+}
+
+// exchange sends the message to a single address and reads the reply, giving
+// up when attemptCtx is done
+func (r *TCPEchoResource) exchange(ctx, attemptCtx context.Context, data *TCPEchoResourceModel, addr string, message []byte, readOpts tcpcheck.ReadOptions) tcpEchoExchange {
+	var ex tcpEchoExchange
+	destStr := net.JoinHostPort(addr, strconv.Itoa(int(data.Port.ValueInt64())))
+
+	conn, err := dialContext(attemptCtx, time.Duration(data.ConnectionTimeout.ValueInt64())*time.Millisecond, destStr)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("dial %q failed: %v", destStr, err.Error()))
+		return ex
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(message)
+	if err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("write to server failed: %v", err.Error()))
+		return ex
+	}
+
+	deadlineDuration := time.Millisecond * time.Duration(data.SingleAttemptTimeout.ValueInt64())
+	deadline := time.Now().Add(deadlineDuration)
+	err = conn.SetDeadline(deadline)
+	if err != nil && !data.ExpectWriteFailure.ValueBool() {
+		tflog.Warn(ctx, fmt.Sprintf("could not set connection deadline: %v", err.Error()))
+		return ex
+	}
+
+	readOpts.Deadline = deadline
+	ex.connected = true
+	ex.reply, ex.readErr = tcpcheck.Read(conn, readOpts)
+	return ex
+}
+
+// evaluate decides whether the exchange with a single address passed the check
+func (r *TCPEchoResource) evaluate(ctx context.Context, data *TCPEchoResourceModel, diag *diag.Diagnostics, ex tcpEchoExchange, exepctFailure bool, expected []byte, persistentResponseRegex *regexp.Regexp, previousRegexValues map[string]string, addr string) bool {
+	if !ex.connected {
+		return false
+	}
+	reply := ex.reply
+	if ex.readErr != nil {
+		if exepctFailure {
+			// We expected this
+			return true
+		}
+		tflog.Warn(ctx, fmt.Sprintf("read from server failed: %v", ex.readErr.Error()))
+		return false
+	}
+	// At this point, if we expect failure, we can just return the check failed,
+	// as we were expecting it to fail
+	if exepctFailure {
+		return false
+	}
+
+	if persistentResponseRegex != nil {
+		limits := persistentResponseRegex.FindStringIndex(string(reply))
+		if limits == nil {
+			tflog.Warn(ctx, fmt.Sprintf("Got response %q, which does not match regex %q", string(reply), data.PersistentResponseRegex.ValueString()))
+			diag.AddWarning("Check failed", fmt.Sprintf("Got response %q, which does not match regex %q", string(reply), data.PersistentResponseRegex.ValueString()))
+			return false
+		}
+		result := string(reply)[limits[0]:limits[1]]
+		tflog.Info(ctx, fmt.Sprintf("Result: %s", result))
+
+		if previousRegexValues[addr] != result {
+			tflog.Warn(ctx, fmt.Sprintf("Got response %q, which does not match previous attempt %q", result, previousRegexValues[addr]))
+			diag.AddWarning("Check failed", fmt.Sprintf("Got response %q, which does not match previous attempt %q", result, previousRegexValues[addr]))
+
+			previousRegexValues[addr] = result
+			return false
+		}
+	}
+
+	if !bytes.Contains(reply, expected) {
+		tflog.Warn(ctx, fmt.Sprintf("Got response %q, which does not include expected message %q", string(reply), string(expected)))
+		diag.AddWarning("Check failed", fmt.Sprintf("Got response %q, which does not include expected message %q", string(reply), string(expected)))
+		return false
+	}
+
+	return true
+}
+
 // Delete implements resource.Resource
 func (*TCPEchoResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
 	var data *TCPEchoResourceModel
@@ -319,7 +597,7 @@ func (*TCPEchoResource) Metadata(ctx context.Context, req resource.MetadataReque
 }
 
 // Read implements resource.Resource
-func (*TCPEchoResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+func (r *TCPEchoResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
 	var data *TCPEchoResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -327,9 +605,22 @@ func (*TCPEchoResource) Read(ctx context.Context, req resource.ReadRequest, resp
 		return
 	}
 
+	if check := prepareRecheck(data); check != nil {
+		r.TCPEcho(ctx, check, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Passed = check.Passed
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// ModifyPlan implements resource.ResourceWithModifyPlan
+func (*TCPEchoResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	planRecheckReplacement(ctx, req, resp)
+}
+
 // Update implements resource.Resource
 func (r *TCPEchoResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var data *TCPEchoResourceModel
@@ -347,6 +638,41 @@ func (r *TCPEchoResource) Update(ctx context.Context, req resource.UpdateRequest
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
+// setResponse stores the response as text when it is valid UTF-8 and as
+// base64 otherwise.
+func setResponse(data *TCPEchoResourceModel, reply []byte) {
+	if utf8.Valid(reply) {
+		data.Response = types.StringValue(string(reply))
+		data.ResponseBase64 = types.StringValue("")
+	} else {
+		data.Response = types.StringValue("")
+		data.ResponseBase64 = types.StringValue(base64.StdEncoding.EncodeToString(reply))
+	}
+}
+
+// hexPayloadRegex matches hex encoded bytes, optionally separated by whitespace
+var hexPayloadRegex = regexp.MustCompile(`^(\s*[0-9a-fA-F]{2})*\s*$`)
+
+// decodePayload returns the bytes of whichever of the text, hex or base64
+// representations of a payload is set.
+func decodePayload(text, hexStr, b64 types.String) ([]byte, error) {
+	switch {
+	case !hexStr.IsNull() && !hexStr.IsUnknown():
+		b, err := hex.DecodeString(strings.Join(strings.Fields(hexStr.ValueString()), ""))
+		if err != nil {
+			return nil, fmt.Errorf("decode hex payload: %w", err)
+		}
+		return b, nil
+	case !b64.IsNull() && !b64.IsUnknown():
+		b, err := base64.StdEncoding.DecodeString(b64.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("decode base64 payload: %w", err)
+		}
+		return b, nil
+	}
+	return []byte(text.ValueString()), nil
+}
+
 func NewTCPEchoResource() resource.Resource {
 	return &TCPEchoResource{}
 }
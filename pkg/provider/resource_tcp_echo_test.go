@@ -16,6 +16,10 @@ package provider
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -60,6 +64,125 @@ func TestAccTCPEchoResource(t *testing.T) {
 	})
 }
 
+func TestAccTCPEchoResourceBinary(t *testing.T) {
+	port := startTCPEchoServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testTCPEchoResourceBinary("test_hex", port, `message_hex = "00 ff 2a 0d 0a"`, `expected_hex = "00ff2a"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_hex", "passed", "true"),
+				),
+			},
+			{
+				Config: testTCPEchoResourceBinary("test_base64", port, `message_base64 = "AP8q"`, `expected_base64 = "AP8q"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_base64", "passed", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTCPEchoResourceReadUntil(t *testing.T) {
+	port := startTCPEchoServer(t)
+	message := strings.Repeat("0123456789", 500)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testTCPEchoResourceReadUntil("test_delimiter", port, message, `read_until = "delimiter"
+	read_delimiter = "\n"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_delimiter", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_delimiter", "response", message+"\n"),
+				),
+			},
+			{
+				Config: testTCPEchoResourceReadUntil("test_bytes", port, message, `read_until = "bytes"
+	read_bytes = 20`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_bytes", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_bytes", "response", message[:20]),
+				),
+			},
+			{
+				Config: testTCPEchoResourceReadUntil("test_idle", port, message, `read_until = "idle"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_idle", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_tcp_echo.test_idle", "response", message+"\n"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccTCPEchoResourceValidation(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testTCPEchoResourceValidation("test_missing_expected", ``),
+				ExpectError: regexp.MustCompile("Missing expected message"),
+			},
+			{
+				Config: testTCPEchoResourceValidation("test_expected_with_failure", `expected_message = "hello"
+	expect_write_failure = true`),
+				ExpectError: regexp.MustCompile("expected_message can't be set when expect_write_failure is true"),
+			},
+			{
+				Config: testTCPEchoResourceValidation("test_bad_regex", `expected_message = "hello"
+	persistent_response_regex = "[a-z"`),
+				ExpectError: regexp.MustCompile("Invalid regex"),
+			},
+			{
+				Config: testTCPEchoResourceValidation("test_missing_delimiter", `expected_message = "hello"
+	read_until = "delimiter"`),
+				ExpectError: regexp.MustCompile("read_delimiter is required"),
+			},
+			{
+				Config: testTCPEchoResourceValidation("test_unused_bytes", `expected_message = "hello"
+	read_bytes = 5`),
+				ExpectError: regexp.MustCompile("read_bytes is only used"),
+			},
+			{
+				Config:      testTCPEchoResourceValidation("test_bad_base64", `expected_base64 = "aGVsbG8"`),
+				ExpectError: regexp.MustCompile("Invalid base64"),
+			},
+		},
+	})
+}
+
+// startTCPEchoServer starts a TCP server on localhost that writes back
+// whatever it reads, and returns the port it's listening on.
+func startTCPEchoServer(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
 func testAccTCPEchoResourceConfig(name, host string, port int, message, expected_message string, ignore_failure bool) string {
 	return fmt.Sprintf(`
 resource "checkmate_tcp_echo" %q {
@@ -87,3 +210,39 @@ resource "checkmate_tcp_echo" %q {
 }`, name, regex, ignore_failure)
 
 }
+
+func testTCPEchoResourceBinary(name string, port int, message, expected string) string {
+	return fmt.Sprintf(`
+resource "checkmate_tcp_echo" %q {
+	host = "127.0.0.1"
+	port = %d
+	%s
+	%s
+	append_newline = false
+	timeout = 1000
+}`, name, port, message, expected)
+
+}
+
+func testTCPEchoResourceReadUntil(name string, port int, message, readUntil string) string {
+	return fmt.Sprintf(`
+resource "checkmate_tcp_echo" %q {
+	host = "127.0.0.1"
+	port = %d
+	message = %q
+	expected_message = %q
+	%s
+	timeout = 1000
+}`, name, port, message, message[:20], readUntil)
+
+}
+
+func testTCPEchoResourceValidation(name, extra string) string {
+	return fmt.Sprintf(`
+resource "checkmate_tcp_echo" %q {
+	host = "127.0.0.1"
+	port = 1
+	message = "hello"
+	%s
+}`, name, extra)
+}
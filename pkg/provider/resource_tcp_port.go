@@ -0,0 +1,427 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/helpers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/modifiers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/tcpcheck"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/validators"
+)
+
+var _ resource.Resource = &TCPPortResource{}
+var _ resource.ResourceWithImportState = &TCPPortResource{}
+var _ resource.ResourceWithModifyPlan = &TCPPortResource{}
+
+type TCPPortResource struct{}
+
+// Schema implements resource.Resource
+func (*TCPPortResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "TCP Port. Checks whether a port is accepting connections",
+
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				MarkdownDescription: "The hostname to connect to",
+				Required:            true,
+			},
+			"port": schema.Int64Attribute{
+				MarkdownDescription: "The port of the hostname to connect to",
+				Required:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 65535),
+				},
+			},
+			"banner_regex": schema.StringAttribute{
+				MarkdownDescription: "Optional regular expression the data sent by the server right after connecting must match. If not set, the check passes as soon as the connection is established. Ignored when `expect_closed` is true",
+				Optional:            true,
+				Validators:          []validator.String{validators.Regex()},
+			},
+			"expect_closed": schema.BoolAttribute{
+				MarkdownDescription: "If true, the check passes only if the connection is refused or times out, which is useful to assert firewall rules. Other errors, like a host that does not resolve, fail the attempt. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"timeout": schema.Int64Attribute{
+				MarkdownDescription: "Overall timeout in milliseconds for the check before giving up, default 10000",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(10000)},
+			},
+			"connection_timeout": schema.Int64Attribute{
+				MarkdownDescription: "The timeout for stablishing a new TCP connection in milliseconds",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(5000)},
+			},
+			"single_attempt_timeout": schema.Int64Attribute{
+				MarkdownDescription: "Timeout for reading the banner in an individual attempt. If exceeded, the attempt will be considered failure and potentially retried. Default 5000ms",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(5000)},
+			},
+			"interval": schema.Int64Attribute{
+				MarkdownDescription: "Interval in milliseconds between attemps. Default 200",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(200)},
+			},
+			"consecutive_successes": schema.Int64Attribute{
+				MarkdownDescription: "Number of consecutive successes required before the check is considered successful overall. Defaults to 1.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(1)},
+			},
+			"all_addresses": schema.BoolAttribute{
+				MarkdownDescription: "If true, every address `host` resolves to is checked concurrently and `quorum` decides whether the attempt passed. Defaults to false.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(false),
+			},
+			"quorum": schema.StringAttribute{
+				MarkdownDescription: "How many addresses have to pass for an attempt to pass when `all_addresses` is true. Either `all`, `any` or a number. Default `all`",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{modifiers.DefaultString(helpers.QuorumAll)},
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(helpers.QuorumRegex, "must be \"all\", \"any\" or a positive number"),
+				},
+			},
+			"address_results": schema.MapAttribute{
+				ElementType:         types.BoolType,
+				MarkdownDescription: "Whether each checked address passed in the last attempt, keyed by address",
+				Computed:            true,
+			},
+			"remote_address": schema.StringAttribute{
+				MarkdownDescription: "Address the connection was established to in the last attempt, after resolving `host`. When checking all addresses, this is the result for the first one",
+				Computed:            true,
+			},
+			"connect_latency_ms": schema.Int64Attribute{
+				MarkdownDescription: "Time in milliseconds it took to establish the connection in the last attempt",
+				Computed:            true,
+			},
+			"banner": schema.StringAttribute{
+				MarkdownDescription: "Data received from the server up to the end of the `banner_regex` match in the last attempt",
+				Computed:            true,
+			},
+			"passed": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "True if the check passed",
+			},
+			"recheck_on_refresh": recheckOnRefreshAttribute(),
+			"create_anyway_on_check_failure": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "If false, the resource will fail to create if the check does not pass. If true, the resource will be created anyway. Defaults to false.",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier",
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"keepers": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary map of string values that when changed will cause the check to run again.",
+				Optional:            true,
+			},
+		}}
+}
+
+type TCPPortResourceModel struct {
+	Id                   types.String `tfsdk:"id"`
+	Host                 types.String `tfsdk:"host"`
+	Port                 types.Int64  `tfsdk:"port"`
+	BannerRegex          types.String `tfsdk:"banner_regex"`
+	ExpectClosed         types.Bool   `tfsdk:"expect_closed"`
+	ConnectionTimeout    types.Int64  `tfsdk:"connection_timeout"`
+	SingleAttemptTimeout types.Int64  `tfsdk:"single_attempt_timeout"`
+	Timeout              types.Int64  `tfsdk:"timeout"`
+	Interval             types.Int64  `tfsdk:"interval"`
+	ConsecutiveSuccesses types.Int64  `tfsdk:"consecutive_successes"`
+	AllAddresses         types.Bool   `tfsdk:"all_addresses"`
+	Quorum               types.String `tfsdk:"quorum"`
+	AddressResults       types.Map    `tfsdk:"address_results"`
+	RemoteAddress        types.String `tfsdk:"remote_address"`
+	ConnectLatency       types.Int64  `tfsdk:"connect_latency_ms"`
+	Banner               types.String `tfsdk:"banner"`
+	IgnoreFailure        types.Bool   `tfsdk:"create_anyway_on_check_failure"`
+	Passed               types.Bool   `tfsdk:"passed"`
+	RecheckOnRefresh     types.String `tfsdk:"recheck_on_refresh"`
+	Keepers              types.Map    `tfsdk:"keepers"`
+
+	// maxAttempts limits the attempts of the check if it's positive
+	maxAttempts int
+}
+
+// recheckFields implements recheckModel
+func (m *TCPPortResourceModel) recheckFields() recheckFields {
+	return recheckFields{
+		mode:                 m.RecheckOnRefresh,
+		ignoreFailure:        &m.IgnoreFailure,
+		consecutiveSuccesses: &m.ConsecutiveSuccesses,
+		maxAttempts:          &m.maxAttempts,
+	}
+}
+
+// ImportState implements resource.ResourceWithImportState
+func (*TCPPortResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// Create implements resource.Resource
+func (r *TCPPortResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data TCPPortResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(uuid.NewString())
+
+	r.TCPPort(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *TCPPortResource) TCPPort(ctx context.Context, data *TCPPortResourceModel, diag *diag.Diagnostics) {
+	var bannerRegex *regexp.Regexp
+	var err error
+	if data.BannerRegex.ValueString() != "" {
+		bannerRegex, err = regexp.Compile(data.BannerRegex.ValueString())
+		if err != nil {
+			diag.AddAttributeError(path.Root("banner_regex"), "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", data.BannerRegex.ValueString(), err.Error()))
+			return
+		}
+	}
+
+	quorum, err := helpers.ParseQuorum(data.Quorum.ValueString())
+	if err != nil {
+		diag.AddAttributeError(path.Root("quorum"), "Invalid quorum", err.Error())
+		return
+	}
+
+	data.Passed = types.BoolValue(false)
+	data.AddressResults = types.MapValueMust(types.BoolType, map[string]attr.Value{})
+	data.RemoteAddress = types.StringValue("")
+	data.ConnectLatency = types.Int64Value(0)
+	data.Banner = types.StringValue("")
+
+	window := helpers.RetryWindow{
+		Context:              ctx,
+		Timeout:              time.Duration(data.Timeout.ValueInt64()) * time.Millisecond,
+		Interval:             time.Duration(data.Interval.ValueInt64()) * time.Millisecond,
+		ConsecutiveSuccesses: int(data.ConsecutiveSuccesses.ValueInt64()),
+		MaxAttempts:          data.maxAttempts,
+	}
+
+	result := window.DoContext(func(attemptCtx context.Context, attempt int, successes int) bool {
+		addrs, err := addressesToCheck(attemptCtx, data.Host.ValueString(), data.AllAddresses.ValueBool())
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d resolve %q failed: %v", attempt, data.Host.ValueString(), err.Error()))
+			return false
+		}
+
+		results := helpers.FanOut(addrs, func(addr string) tcpPortResult {
+			return r.probe(ctx, attemptCtx, data, bannerRegex, attempt, addr)
+		})
+
+		passed := make([]bool, len(results))
+		passedCount := 0
+		for i, res := range results {
+			passed[i] = res.passed
+			if res.passed {
+				passedCount++
+			}
+		}
+		data.AddressResults = addressResultsValue(addrs, passed)
+		data.RemoteAddress = types.StringValue(results[0].remoteAddress)
+		data.ConnectLatency = types.Int64Value(results[0].latency)
+		data.Banner = types.StringValue(results[0].banner)
+
+		if !quorum.Satisfied(passedCount, len(results)) {
+			tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d %d/%d addresses passed, quorum is %s", attempt, passedCount, len(results), data.Quorum.ValueString()))
+			return false
+		}
+		tflog.Trace(ctx, fmt.Sprintf("SUCCESS [%d/%d] %d/%d addresses passed", successes, data.ConsecutiveSuccesses.ValueInt64(), passedCount, len(results)))
+		return true
+	})
+
+	switch result {
+	case helpers.Success:
+		data.Passed = types.BoolValue(true)
+	case helpers.TimeoutExceeded:
+		diag.AddWarning("Timeout exceeded", fmt.Sprintf("Timeout of %d milliseconds exceeded", data.Timeout.ValueInt64()))
+		if !data.IgnoreFailure.ValueBool() {
+			diag.AddError("Check failed", "The check did not pass and create_anyway_on_check_failure is false")
+			return
+		}
+	}
+}
+
+type tcpPortResult struct {
+	passed        bool
+	remoteAddress string
+	latency       int64
+	banner        string
+}
+
+// probe checks a single address, giving up when attemptCtx is done
+func (r *TCPPortResource) probe(ctx, attemptCtx context.Context, data *TCPPortResourceModel, bannerRegex *regexp.Regexp, attempt int, addr string) tcpPortResult {
+	var res tcpPortResult
+	expectClosed := data.ExpectClosed.ValueBool()
+	destStr := net.JoinHostPort(addr, strconv.Itoa(int(data.Port.ValueInt64())))
+
+	start := time.Now()
+	conn, err := dialContext(attemptCtx, time.Duration(data.ConnectionTimeout.ValueInt64())*time.Millisecond, destStr)
+	if err != nil {
+		if expectClosed && dialClosed(err) {
+			tflog.Trace(ctx, fmt.Sprintf("ATTEMPT #%d dial %q failed as expected: %v", attempt, destStr, err.Error()))
+			res.passed = true
+			return res
+		}
+		tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d dial %q failed: %v", attempt, destStr, err.Error()))
+		return res
+	}
+	defer conn.Close()
+	res.latency = time.Since(start).Milliseconds()
+	res.remoteAddress = conn.RemoteAddr().String()
+
+	if expectClosed {
+		tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d connected to %s, which was expected to be closed", attempt, conn.RemoteAddr()))
+		return res
+	}
+
+	if bannerRegex != nil {
+		deadlineDuration := time.Millisecond * time.Duration(data.SingleAttemptTimeout.ValueInt64())
+		err = conn.SetDeadline(time.Now().Add(deadlineDuration))
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("could not set connection deadline: %v", err.Error()))
+			return res
+		}
+		received, _, err := tcpcheck.NewConversation(conn, 0).Expect(bannerRegex)
+		res.banner = strings.ToValidUTF8(string(received), "�")
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d got banner %q from %s: %v", attempt, string(received), conn.RemoteAddr(), err.Error()))
+			return res
+		}
+	}
+
+	res.passed = true
+	return res
+}
+
+// dialClosed reports whether a dial error means the port is closed or
+// filtered: the connection was refused or timed out. Other errors, like a host
+// that doesn't resolve, don't say anything about the port.
+func dialClosed(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return false
+	}
+	if errors.Is(err, errConnRefused) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Delete implements resource.Resource
+func (*TCPPortResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *TCPPortResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Metadata implements resource.Resource
+func (*TCPPortResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_tcp_port"
+}
+
+// Read implements resource.Resource
+func (r *TCPPortResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *TCPPortResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if check := prepareRecheck(data); check != nil {
+		r.TCPPort(ctx, check, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Passed = check.Passed
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// ModifyPlan implements resource.ResourceWithModifyPlan
+func (*TCPPortResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	planRecheckReplacement(ctx, req, resp)
+}
+
+// Update implements resource.Resource
+func (r *TCPPortResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *TCPPortResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.TCPPort(ctx, data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func NewTCPPortResource() resource.Resource {
+	return &TCPPortResource{}
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccTCPPortResource(t *testing.T) {
+	openPort := startLineServer(t)
+	closedPort := closedTCPPort(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccTCPPortResourceConfig("test_open", openPort, `banner_regex = "^220 banner"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_open", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_open", "banner", "220 banner"),
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_open", "remote_address", fmt.Sprintf("127.0.0.1:%d", openPort)),
+					resource.TestCheckResourceAttrSet("checkmate_tcp_port.test_open", "connect_latency_ms"),
+				),
+			},
+			{
+				Config: testAccTCPPortResourceConfig("test_all_addresses", openPort, "all_addresses = true\n\tquorum = \"any\""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_all_addresses", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_all_addresses", "address_results.%", "1"),
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_all_addresses", "address_results.127.0.0.1", "true"),
+				),
+			},
+			{
+				Config: testAccTCPPortResourceConfig("test_closed", closedPort, `expect_closed = true`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_closed", "passed", "true"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(`
+resource "checkmate_tcp_port" "test_closed_unresolved" {
+	host = "checkmate.invalid"
+	port = %d
+	timeout = 1000
+	expect_closed = true
+	create_anyway_on_check_failure = true
+}`, closedPort),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_closed_unresolved", "passed", "false"),
+				),
+			},
+			{
+				Config: testAccTCPPortResourceConfig("test_closed_failure", closedPort, `create_anyway_on_check_failure = true`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_port.test_closed_failure", "passed", "false"),
+				),
+			},
+		},
+	})
+}
+
+// closedTCPPort returns a port on localhost nothing is listening on.
+func closedTCPPort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func testAccTCPPortResourceConfig(name string, port int, extra string) string {
+	return fmt.Sprintf(`
+resource "checkmate_tcp_port" %q {
+	host = "127.0.0.1"
+	port = %d
+	timeout = 1000
+	%s
+}`, name, port, extra)
+
+}
+
+func TestDialClosed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", errConnRefused)}, true},
+		{"timeout", &net.OpError{Op: "dial", Err: ctx.Err()}, true},
+		{"not found", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "checkmate.invalid", IsNotFound: true}}, false},
+		{"resolver timeout", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", Name: "checkmate.invalid", IsTimeout: true}}, false},
+		{"unreachable", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := dialClosed(tt.err); got != tt.want {
+			t.Errorf("%s: dialClosed(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
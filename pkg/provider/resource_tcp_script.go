@@ -0,0 +1,412 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/helpers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/modifiers"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/tcpcheck"
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/validators"
+)
+
+var _ resource.Resource = &TCPScriptResource{}
+var _ resource.ResourceWithImportState = &TCPScriptResource{}
+var _ resource.ResourceWithModifyPlan = &TCPScriptResource{}
+
+type TCPScriptResource struct{}
+
+// Schema implements resource.Resource
+func (*TCPScriptResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "TCP Script. Runs a send/expect conversation over a TCP connection",
+
+		Attributes: map[string]schema.Attribute{
+			"host": schema.StringAttribute{
+				MarkdownDescription: "The hostname to connect to",
+				Required:            true,
+			},
+			"port": schema.Int64Attribute{
+				MarkdownDescription: "The port of the hostname to connect to",
+				Required:            true,
+				Validators: []validator.Int64{
+					int64validator.Between(1, 65535),
+				},
+			},
+			"steps": schema.ListNestedAttribute{
+				MarkdownDescription: "Steps of the conversation, run in order on the same connection",
+				Required:            true,
+				Validators: []validator.List{
+					listvalidator.SizeAtLeast(1),
+				},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"send": schema.StringAttribute{
+							MarkdownDescription: "Data to send. No newline is appended, include it in the value if the protocol needs one",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.AtLeastOneOf(
+									path.MatchRelative().AtParent().AtName("send_hex"),
+									path.MatchRelative().AtParent().AtName("send_base64"),
+									path.MatchRelative().AtParent().AtName("expect"),
+								),
+								stringvalidator.ConflictsWith(
+									path.MatchRelative().AtParent().AtName("send_hex"),
+									path.MatchRelative().AtParent().AtName("send_base64"),
+								),
+							},
+						},
+						"send_hex": schema.StringAttribute{
+							MarkdownDescription: "Data to send, as hex encoded bytes. Whitespace is ignored",
+							Optional:            true,
+							Validators: []validator.String{
+								stringvalidator.RegexMatches(hexPayloadRegex, "must be hex encoded bytes"),
+								stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName("send_base64")),
+							},
+						},
+						"send_base64": schema.StringAttribute{
+							MarkdownDescription: "Data to send, as base64 encoded bytes",
+							Optional:            true,
+							Validators:          []validator.String{validators.Base64()},
+						},
+						"expect": schema.StringAttribute{
+							MarkdownDescription: "Regular expression the received data must match after sending. Data is read until it matches or the attempt times out. Named capture groups are exported in `captures`",
+							Optional:            true,
+							Validators:          []validator.String{validators.Regex()},
+						},
+					},
+				},
+			},
+			"timeout": schema.Int64Attribute{
+				MarkdownDescription: "Overall timeout in milliseconds for the check before giving up, default 10000",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(10000)},
+			},
+			"connection_timeout": schema.Int64Attribute{
+				MarkdownDescription: "The timeout for stablishing a new TCP connection in milliseconds",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(5000)},
+			},
+			"single_attempt_timeout": schema.Int64Attribute{
+				MarkdownDescription: "Timeout for the whole conversation in an individual attempt. If exceeded, the attempt will be considered failure and potentially retried. Default 5000ms",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(5000)},
+			},
+			"interval": schema.Int64Attribute{
+				MarkdownDescription: "Interval in milliseconds between attemps. Default 200",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(200)},
+			},
+			"consecutive_successes": schema.Int64Attribute{
+				MarkdownDescription: "Number of consecutive successes required before the check is considered successful overall. Defaults to 1.",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(1)},
+			},
+			"max_response_bytes": schema.Int64Attribute{
+				MarkdownDescription: "Maximum number of bytes to buffer while waiting for an expectation to match. Default 65536",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.Int64{modifiers.DefaultInt64(65536)},
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"captures": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Values captured by the `expect` expressions in the last attempt. Named groups are keyed by their name, unnamed groups by `<step index>.<group index>`",
+				Computed:            true,
+			},
+			"transcript": schema.StringAttribute{
+				MarkdownDescription: "Everything received from the server in the last attempt",
+				Computed:            true,
+			},
+			"passed": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "True if the check passed",
+			},
+			"recheck_on_refresh": recheckOnRefreshAttribute(),
+			"create_anyway_on_check_failure": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "If false, the resource will fail to create if the check does not pass. If true, the resource will be created anyway. Defaults to false.",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Identifier",
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"keepers": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary map of string values that when changed will cause the check to run again.",
+				Optional:            true,
+			},
+		}}
+}
+
+type TCPScriptStepModel struct {
+	Send       types.String `tfsdk:"send"`
+	SendHex    types.String `tfsdk:"send_hex"`
+	SendBase64 types.String `tfsdk:"send_base64"`
+	Expect     types.String `tfsdk:"expect"`
+}
+
+type TCPScriptResourceModel struct {
+	Id                   types.String         `tfsdk:"id"`
+	Host                 types.String         `tfsdk:"host"`
+	Port                 types.Int64          `tfsdk:"port"`
+	Steps                []TCPScriptStepModel `tfsdk:"steps"`
+	ConnectionTimeout    types.Int64          `tfsdk:"connection_timeout"`
+	SingleAttemptTimeout types.Int64          `tfsdk:"single_attempt_timeout"`
+	Timeout              types.Int64          `tfsdk:"timeout"`
+	Interval             types.Int64          `tfsdk:"interval"`
+	ConsecutiveSuccesses types.Int64          `tfsdk:"consecutive_successes"`
+	MaxResponseBytes     types.Int64          `tfsdk:"max_response_bytes"`
+	Captures             types.Map            `tfsdk:"captures"`
+	Transcript           types.String         `tfsdk:"transcript"`
+	IgnoreFailure        types.Bool           `tfsdk:"create_anyway_on_check_failure"`
+	Passed               types.Bool           `tfsdk:"passed"`
+	RecheckOnRefresh     types.String         `tfsdk:"recheck_on_refresh"`
+	Keepers              types.Map            `tfsdk:"keepers"`
+
+	// maxAttempts limits the attempts of the check if it's positive
+	maxAttempts int
+}
+
+// recheckFields implements recheckModel
+func (m *TCPScriptResourceModel) recheckFields() recheckFields {
+	return recheckFields{
+		mode:                 m.RecheckOnRefresh,
+		ignoreFailure:        &m.IgnoreFailure,
+		consecutiveSuccesses: &m.ConsecutiveSuccesses,
+		maxAttempts:          &m.maxAttempts,
+	}
+}
+
+// tcpScriptStep is a step with its payload decoded and expectation compiled
+type tcpScriptStep struct {
+	send   []byte
+	expect *regexp.Regexp
+}
+
+// ImportState implements resource.ResourceWithImportState
+func (*TCPScriptResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// Create implements resource.Resource
+func (r *TCPScriptResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data TCPScriptResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(uuid.NewString())
+
+	r.TCPScript(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *TCPScriptResource) TCPScript(ctx context.Context, data *TCPScriptResourceModel, diag *diag.Diagnostics) {
+	steps := make([]tcpScriptStep, 0, len(data.Steps))
+	for i, s := range data.Steps {
+		send, err := decodePayload(s.Send, s.SendHex, s.SendBase64)
+		if err != nil {
+			diag.AddAttributeError(path.Root("steps").AtListIndex(i), "Invalid step", err.Error())
+			return
+		}
+		step := tcpScriptStep{send: send}
+		if s.Expect.ValueString() != "" {
+			step.expect, err = regexp.Compile(s.Expect.ValueString())
+			if err != nil {
+				diag.AddAttributeError(path.Root("steps").AtListIndex(i).AtName("expect"), "Invalid regex", fmt.Sprintf("Could not compile regex %q: %v", s.Expect.ValueString(), err.Error()))
+				return
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	data.Passed = types.BoolValue(false)
+	data.Captures = types.MapValueMust(types.StringType, map[string]attr.Value{})
+	data.Transcript = types.StringValue("")
+
+	window := helpers.RetryWindow{
+		Context:              ctx,
+		Timeout:              time.Duration(data.Timeout.ValueInt64()) * time.Millisecond,
+		Interval:             time.Duration(data.Interval.ValueInt64()) * time.Millisecond,
+		ConsecutiveSuccesses: int(data.ConsecutiveSuccesses.ValueInt64()),
+		MaxAttempts:          data.maxAttempts,
+	}
+
+	result := window.DoContext(func(attemptCtx context.Context, attempt int, successes int) bool {
+		destStr := net.JoinHostPort(data.Host.ValueString(), strconv.Itoa(int(data.Port.ValueInt64())))
+
+		conn, err := dialContext(attemptCtx, time.Duration(data.ConnectionTimeout.ValueInt64())*time.Millisecond, destStr)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("dial %q failed: %v", destStr, err.Error()))
+			return false
+		}
+		defer conn.Close()
+
+		// the whole conversation has to fit in a single attempt
+		deadlineDuration := time.Millisecond * time.Duration(data.SingleAttemptTimeout.ValueInt64())
+		err = conn.SetDeadline(time.Now().Add(deadlineDuration))
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("could not set connection deadline: %v", err.Error()))
+			return false
+		}
+
+		conversation := tcpcheck.NewConversation(conn, int(data.MaxResponseBytes.ValueInt64()))
+		var transcript strings.Builder
+		captures := make(map[string]attr.Value)
+		defer func() {
+			data.Transcript = types.StringValue(strings.ToValidUTF8(transcript.String(), "�"))
+			data.Captures = types.MapValueMust(types.StringType, captures)
+		}()
+
+		for i, step := range steps {
+			if len(step.send) > 0 {
+				if err := conversation.Send(step.send); err != nil {
+					tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d step %d: write to server failed: %v", attempt, i, err.Error()))
+					return false
+				}
+			}
+			if step.expect == nil {
+				continue
+			}
+			received, matches, err := conversation.Expect(step.expect)
+			transcript.Write(received)
+			if err != nil {
+				tflog.Warn(ctx, fmt.Sprintf("ATTEMPT #%d step %d: got %q: %v", attempt, i, string(received), err.Error()))
+				return false
+			}
+			for g, name := range step.expect.SubexpNames() {
+				if g == 0 {
+					continue
+				}
+				if name == "" {
+					name = fmt.Sprintf("%d.%d", i, g)
+				}
+				captures[name] = types.StringValue(matches[g])
+			}
+		}
+
+		tflog.Trace(ctx, fmt.Sprintf("SUCCESS [%d/%d]", successes, data.ConsecutiveSuccesses.ValueInt64()))
+		return true
+	})
+
+	switch result {
+	case helpers.Success:
+		data.Passed = types.BoolValue(true)
+	case helpers.TimeoutExceeded:
+		diag.AddWarning("Timeout exceeded", fmt.Sprintf("Timeout of %d milliseconds exceeded", data.Timeout.ValueInt64()))
+		if !data.IgnoreFailure.ValueBool() {
+			diag.AddError("Check failed", "The check did not pass and create_anyway_on_check_failure is false")
+			return
+		}
+	}
+}
+
+// Delete implements resource.Resource
+func (*TCPScriptResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *TCPScriptResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
+
+// Metadata implements resource.Resource
+func (*TCPScriptResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_tcp_script"
+}
+
+// Read implements resource.Resource
+func (r *TCPScriptResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *TCPScriptResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if check := prepareRecheck(data); check != nil {
+		r.TCPScript(ctx, check, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.Passed = check.Passed
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// ModifyPlan implements resource.ResourceWithModifyPlan
+func (*TCPScriptResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	planRecheckReplacement(ctx, req, resp)
+}
+
+// Update implements resource.Resource
+func (r *TCPScriptResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *TCPScriptResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.TCPScript(ctx, data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func NewTCPScriptResource() resource.Resource {
+	return &TCPScriptResource{}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccTCPScriptResource(t *testing.T) {
+	port := startLineServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource "checkmate_tcp_script" "test_bad_base64" {
+	host = "127.0.0.1"
+	port = %d
+	steps = [{ send_base64 = "SEVMTw" }]
+}`, port),
+				ExpectError: regexp.MustCompile("Invalid base64"),
+			},
+			{
+				Config: testAccTCPScriptResourceConfig("test_success", port, "250 hello (?P<name>\\\\w+)", false),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_script.test_success", "passed", "true"),
+					resource.TestCheckResourceAttr("checkmate_tcp_script.test_success", "captures.version", "1.0"),
+					resource.TestCheckResourceAttr("checkmate_tcp_script.test_success", "captures.name", "checkmate"),
+					resource.TestCheckResourceAttr("checkmate_tcp_script.test_success", "transcript", "220 banner v1.0\r\n250 hello checkmate"),
+				),
+			},
+			{
+				Config: testAccTCPScriptResourceConfig("test_failure", port, "500 .*", true),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("checkmate_tcp_script.test_failure", "passed", "false"),
+				),
+			},
+		},
+	})
+}
+
+// startLineServer starts a TCP server on localhost that sends a banner and
+// then greets every HELO line it receives, and returns the port it's listening on.
+func startLineServer(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "220 banner v1.0\r\n")
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fmt.Fprintf(conn, "250 hello %s\r\n", strings.TrimPrefix(strings.TrimSpace(line), "HELO "))
+				}
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func testAccTCPScriptResourceConfig(name string, port int, expect string, ignoreFailure bool) string {
+	return fmt.Sprintf(`
+resource "checkmate_tcp_script" %q {
+	host = "127.0.0.1"
+	port = %d
+	timeout = 1000
+	steps = [
+		{
+			expect = "220 banner v(?P<version>[0-9.]+)\r\n"
+		},
+		{
+			send = "HELO checkmate\r\n"
+			expect = "%s"
+		},
+	]
+	create_anyway_on_check_failure = %t
+}`, name, port, expect, ignoreFailure)
+
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcpcheck
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+)
+
+// Conversation drives a send/expect dialogue over a connection. Data received
+// after a match is kept for the next expectation.
+type Conversation struct {
+	conn     net.Conn
+	maxBytes int
+	buf      []byte
+}
+
+func NewConversation(conn net.Conn, maxBytes int) *Conversation {
+	if maxBytes <= 0 {
+		maxBytes = 1024
+	}
+	return &Conversation{conn: conn, maxBytes: maxBytes}
+}
+
+// Send writes b to the connection.
+func (c *Conversation) Send(b []byte) error {
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// Expect reads from the connection until re matches the received data. It
+// returns everything received up to the end of the match along with the
+// submatches, the first being the whole match. On error, the data received so
+// far is returned.
+func (c *Conversation) Expect(re *regexp.Regexp) ([]byte, []string, error) {
+	chunk := make([]byte, 1024)
+	for {
+		if loc := re.FindSubmatchIndex(c.buf); loc != nil {
+			received := c.buf[:loc[1]]
+			matches := make([]string, len(loc)/2)
+			for i := range matches {
+				if loc[2*i] >= 0 {
+					matches[i] = string(c.buf[loc[2*i]:loc[2*i+1]])
+				}
+			}
+			c.buf = c.buf[loc[1]:]
+			return received, matches, nil
+		}
+		if len(c.buf) >= c.maxBytes {
+			return c.buf, nil, fmt.Errorf("%w while waiting for %q", ErrResponseTooLarge, re)
+		}
+		n, err := c.conn.Read(chunk[:min(len(chunk), c.maxBytes-len(c.buf))])
+		c.buf = append(c.buf, chunk[:n]...)
+		if err != nil && re.Match(c.buf) {
+			// the connection may have been closed right after the data we
+			// are waiting for
+			continue
+		}
+		if err != nil {
+			return c.buf, nil, fmt.Errorf("waiting for %q: %w", re, err)
+		}
+	}
+}
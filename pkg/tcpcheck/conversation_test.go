@@ -0,0 +1,87 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcpcheck
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestConversation(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		// banner and reply arrive in the same segment, the second expect
+		// has to find the reply in what was left over from the first
+		server.Write([]byte("220 ready\r\n250-first\r\n"))
+		r := bufio.NewReader(server)
+		line, _ := r.ReadString('\n')
+		server.Write([]byte("250 hello " + line))
+	}()
+
+	client.SetDeadline(time.Now().Add(time.Second))
+	c := NewConversation(client, 1024)
+
+	received, matches, err := c.Expect(regexp.MustCompile(`^220 (\w+)\r\n`))
+	if err != nil {
+		t.Fatalf("Expect() error = %v", err)
+	}
+	if string(received) != "220 ready\r\n" {
+		t.Errorf("Expect() received = %q", received)
+	}
+	if want := []string{"220 ready\r\n", "ready"}; !reflect.DeepEqual(matches, want) {
+		t.Errorf("Expect() matches = %q, want %q", matches, want)
+	}
+
+	if _, _, err := c.Expect(regexp.MustCompile(`250-first\r\n`)); err != nil {
+		t.Fatalf("Expect() error = %v", err)
+	}
+
+	if err := c.Send([]byte("checkmate\r\n")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	_, matches, err = c.Expect(regexp.MustCompile(`250 hello (?P<name>\w+)`))
+	if err != nil {
+		t.Fatalf("Expect() error = %v", err)
+	}
+	if matches[1] != "checkmate" {
+		t.Errorf("Expect() matches = %q", matches)
+	}
+
+	if _, _, err := c.Expect(regexp.MustCompile(`never`)); err == nil {
+		t.Errorf("Expect() expected an error after the server closed the connection")
+	}
+}
+
+func TestConversationMaxBytes(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		server.Write([]byte("0123456789"))
+	}()
+
+	client.SetDeadline(time.Now().Add(time.Second))
+	c := NewConversation(client, 5)
+	_, _, err := c.Expect(regexp.MustCompile(`9`))
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expect() error = %v, want %v", err, ErrResponseTooLarge)
+	}
+}
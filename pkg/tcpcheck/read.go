@@ -0,0 +1,165 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcpcheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// Supported values for ReadOptions.Until
+const (
+	// ReadOnce returns whatever a single read from the connection returns.
+	ReadOnce = "once"
+	// ReadDelimiter reads until the delimiter has been received.
+	ReadDelimiter = "delimiter"
+	// ReadBytes reads an exact number of bytes.
+	ReadBytes = "bytes"
+	// ReadEOF reads until the peer closes the connection.
+	ReadEOF = "eof"
+	// ReadIdle reads until the peer stops sending data for a while.
+	ReadIdle = "idle"
+)
+
+// ReadModes lists all the accepted values for ReadOptions.Until
+var ReadModes = []string{ReadOnce, ReadDelimiter, ReadBytes, ReadEOF, ReadIdle}
+
+// ErrResponseTooLarge is returned when the response reaches MaxBytes before
+// the read condition is satisfied.
+var ErrResponseTooLarge = errors.New("response exceeded the maximum size")
+
+type ReadOptions struct {
+	// Until is the read strategy, one of ReadModes. Defaults to ReadOnce.
+	Until string
+	// Delimiter ends the read when using ReadDelimiter. The response includes
+	// the delimiter and discards anything received after it.
+	Delimiter []byte
+	// Bytes is the number of bytes to read when using ReadBytes.
+	Bytes int
+	// IdleTimeout ends the read when using ReadIdle and no data has been
+	// received for that long after the first byte.
+	IdleTimeout time.Duration
+	// MaxBytes caps the size of the response.
+	MaxBytes int
+	// Deadline is the time by which the whole response must have been read.
+	// If zero, the deadline already set on the connection is used.
+	Deadline time.Time
+}
+
+// Read reads a response from conn using the given strategy. On error,
+// whatever was read so far is returned along with the error.
+func Read(conn net.Conn, opts ReadOptions) ([]byte, error) {
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 1024
+	}
+	if !opts.Deadline.IsZero() {
+		if err := conn.SetReadDeadline(opts.Deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	switch opts.Until {
+	case "", ReadOnce:
+		buf := make([]byte, maxBytes)
+		n, err := conn.Read(buf)
+		return buf[:n], err
+	case ReadBytes:
+		if opts.Bytes > maxBytes {
+			return nil, fmt.Errorf("%w: %d bytes requested, the maximum is %d", ErrResponseTooLarge, opts.Bytes, maxBytes)
+		}
+		buf := make([]byte, opts.Bytes)
+		n, err := io.ReadFull(conn, buf)
+		return buf[:n], err
+	case ReadDelimiter:
+		if len(opts.Delimiter) == 0 {
+			return nil, errors.New("empty delimiter")
+		}
+		resp, err := readLoop(conn, maxBytes, func(resp []byte) bool {
+			return bytes.Contains(resp, opts.Delimiter)
+		})
+		if err != nil {
+			return resp, err
+		}
+		// anything received after the delimiter is not part of this response
+		i := bytes.Index(resp, opts.Delimiter)
+		return resp[:i+len(opts.Delimiter)], nil
+	case ReadEOF:
+		resp, err := readLoop(conn, maxBytes, func([]byte) bool { return false })
+		if errors.Is(err, io.EOF) {
+			return resp, nil
+		}
+		return resp, err
+	case ReadIdle:
+		return readIdle(conn, maxBytes, opts.IdleTimeout, opts.Deadline)
+	}
+	return nil, fmt.Errorf("unsupported read mode %q", opts.Until)
+}
+
+// readLoop keeps reading until done returns true, the connection errors or
+// maxBytes have been read.
+func readLoop(conn net.Conn, maxBytes int, done func([]byte) bool) ([]byte, error) {
+	resp := make([]byte, 0, 1024)
+	buf := make([]byte, 1024)
+	for {
+		n, err := conn.Read(buf[:min(len(buf), maxBytes-len(resp))])
+		resp = append(resp, buf[:n]...)
+		if done(resp) {
+			return resp, nil
+		}
+		if err != nil {
+			return resp, err
+		}
+		if len(resp) >= maxBytes {
+			return resp, ErrResponseTooLarge
+		}
+	}
+}
+
+// readIdle reads until no data arrives for idle after the first byte, the
+// deadline expires or maxBytes have been read.
+func readIdle(conn net.Conn, maxBytes int, idle time.Duration, deadline time.Time) ([]byte, error) {
+	resp := make([]byte, 0, 1024)
+	buf := make([]byte, 1024)
+	idleDeadline := false
+	for {
+		n, err := conn.Read(buf[:min(len(buf), maxBytes-len(resp))])
+		resp = append(resp, buf[:n]...)
+		if err != nil {
+			if len(resp) > 0 && (errors.Is(err, io.EOF) || idleDeadline && errors.Is(err, os.ErrDeadlineExceeded)) {
+				return resp, nil
+			}
+			return resp, err
+		}
+		if len(resp) >= maxBytes {
+			return resp, nil
+		}
+		if n > 0 {
+			next := time.Now().Add(idle)
+			idleDeadline = deadline.IsZero() || next.Before(deadline)
+			if !idleDeadline {
+				next = deadline
+			}
+			if err := conn.SetReadDeadline(next); err != nil {
+				return resp, err
+			}
+		}
+	}
+}
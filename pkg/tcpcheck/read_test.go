@@ -0,0 +1,152 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tcpcheck
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name string
+		// chunks are written by the server with a short pause in between
+		chunks    []string
+		keepOpen  bool
+		opts      ReadOptions
+		want      string
+		wantErr   bool
+		wantLarge bool
+	}{
+		{
+			name:   "once returns the first segment",
+			chunks: []string{"hello ", "world"},
+			opts:   ReadOptions{Until: ReadOnce},
+			want:   "hello ",
+		},
+		{
+			name:   "delimiter spanning segments",
+			chunks: []string{"hello ", "world\r", "\nmore"},
+			opts:   ReadOptions{Until: ReadDelimiter, Delimiter: []byte("\r\n")},
+			want:   "hello world\r\n",
+		},
+		{
+			name:     "delimiter never received",
+			chunks:   []string{"hello"},
+			keepOpen: true,
+			opts:     ReadOptions{Until: ReadDelimiter, Delimiter: []byte("\n")},
+			want:     "hello",
+			wantErr:  true,
+		},
+		{
+			name:   "exact byte count",
+			chunks: []string{"hel", "lo world"},
+			opts:   ReadOptions{Until: ReadBytes, Bytes: 5},
+			want:   "hello",
+		},
+		{
+			name:    "byte count not reached",
+			chunks:  []string{"hel"},
+			opts:    ReadOptions{Until: ReadBytes, Bytes: 5},
+			want:    "hel",
+			wantErr: true,
+		},
+		{
+			name:   "until eof",
+			chunks: []string{"hello ", "world"},
+			opts:   ReadOptions{Until: ReadEOF},
+			want:   "hello world",
+		},
+		{
+			name:     "eof never received",
+			chunks:   []string{"hello"},
+			keepOpen: true,
+			opts:     ReadOptions{Until: ReadEOF},
+			want:     "hello",
+			wantErr:  true,
+		},
+		{
+			name:     "until idle",
+			chunks:   []string{"hello ", "world"},
+			keepOpen: true,
+			opts:     ReadOptions{Until: ReadIdle, IdleTimeout: 100 * time.Millisecond},
+			want:     "hello world",
+		},
+		{
+			name:     "idle without any data",
+			keepOpen: true,
+			opts:     ReadOptions{Until: ReadIdle, IdleTimeout: 100 * time.Millisecond},
+			wantErr:  true,
+		},
+		{
+			name:      "max bytes reached before delimiter",
+			chunks:    []string{"hello world"},
+			opts:      ReadOptions{Until: ReadDelimiter, Delimiter: []byte("\n"), MaxBytes: 5},
+			want:      "hello",
+			wantErr:   true,
+			wantLarge: true,
+		},
+		{
+			name:   "max bytes caps a single read",
+			chunks: []string{"hello world"},
+			opts:   ReadOptions{Until: ReadOnce, MaxBytes: 5},
+			want:   "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			// closing the client unblocks pending writes, then wait for the
+			// writer so it doesn't outlive the subtest
+			defer func() {
+				client.Close()
+				close(stop)
+				<-done
+			}()
+			go func() {
+				defer close(done)
+				defer server.Close()
+				for _, c := range tt.chunks {
+					server.Write([]byte(c))
+					time.Sleep(20 * time.Millisecond)
+				}
+				if tt.keepOpen {
+					select {
+					case <-stop:
+					case <-time.After(time.Second):
+					}
+				}
+			}()
+
+			tt.opts.Deadline = time.Now().Add(500 * time.Millisecond)
+			got, err := Read(client, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantLarge && !errors.Is(err, ErrResponseTooLarge) {
+				t.Errorf("Read() error = %v, want %v", err, ErrResponseTooLarge)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
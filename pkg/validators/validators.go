@@ -0,0 +1,120 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validators contains attribute validators that parse values the
+// same way the checks do, so that mistakes are reported at plan time.
+package validators
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/tetratelabs/terraform-provider-checkmate/pkg/healthcheck"
+)
+
+var _ validator.String = stringValidator{}
+
+// Regex validates that the value is a valid regular expression.
+func Regex() validator.String {
+	return stringValidator{
+		description: "value must be a valid regular expression",
+		summary:     "Invalid regex",
+		check: func(value string) error {
+			_, err := regexp.Compile(value)
+			return err
+		},
+	}
+}
+
+// JSONPath validates that the value is a valid JSONPath expression, with the
+// same syntax as kubectl jsonpath output.
+func JSONPath() validator.String {
+	return stringValidator{
+		description: "value must be a valid JSONPath expression",
+		summary:     "Invalid JSONPath expression",
+		check: func(value string) error {
+			return jsonpath.New("parser").Parse(value)
+		},
+	}
+}
+
+// StatusCodePattern validates that the value is a comma separated list of
+// status codes and ranges like `200-299,304`.
+func StatusCodePattern() validator.String {
+	return stringValidator{
+		description: "value must be a comma separated list of status codes and ranges",
+		summary:     "Invalid status code pattern",
+		check:       healthcheck.ValidateStatusCodePattern,
+	}
+}
+
+// ExitCodePattern validates that the value is a comma separated list of exit
+// codes and ranges like `0,2-3`.
+func ExitCodePattern() validator.String {
+	return stringValidator{
+		description: "value must be a comma separated list of exit codes and ranges",
+		summary:     "Invalid exit code pattern",
+		check:       healthcheck.ValidateStatusCodePattern,
+	}
+}
+
+// Base64 validates that the value is standard base64 encoded data.
+func Base64() validator.String {
+	return stringValidator{
+		description: "value must be base64 encoded",
+		summary:     "Invalid base64",
+		// the value can be sensitive, like the contents of a file
+		hideValue: true,
+		check: func(value string) error {
+			_, err := base64.StdEncoding.DecodeString(value)
+			return err
+		},
+	}
+}
+
+// stringValidator reports the error returned by check for known values.
+type stringValidator struct {
+	description string
+	summary     string
+	check       func(value string) error
+	// hideValue leaves the value out of the error
+	hideValue bool
+}
+
+func (v stringValidator) Description(ctx context.Context) string {
+	return v.description
+}
+
+func (v stringValidator) MarkdownDescription(ctx context.Context) string {
+	return v.description
+}
+
+// ValidateString implements validator.String
+func (v stringValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := v.check(req.ConfigValue.ValueString()); err != nil {
+		if v.hideValue {
+			resp.Diagnostics.AddAttributeError(req.Path, v.summary, fmt.Sprintf("The value is not valid: %v", err))
+			return
+		}
+		resp.Diagnostics.AddAttributeError(req.Path, v.summary, fmt.Sprintf("%q is not valid: %v", req.ConfigValue.ValueString(), err))
+	}
+}
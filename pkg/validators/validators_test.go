@@ -0,0 +1,62 @@
+// Copyright 2024 Tetrate
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validators
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator.String
+		value     types.String
+		wantErr   bool
+	}{
+		{"valid regex", Regex(), types.StringValue("^220 (?P<host>\\S+)"), false},
+		{"invalid regex", Regex(), types.StringValue("[a-z"), true},
+		{"valid jsonpath", JSONPath(), types.StringValue("{.items[0].status}"), false},
+		{"invalid jsonpath", JSONPath(), types.StringValue("{.foo"), true},
+		{"single status code", StatusCodePattern(), types.StringValue("200"), false},
+		{"status code ranges", StatusCodePattern(), types.StringValue("200-299,304"), false},
+		{"open status code range", StatusCodePattern(), types.StringValue("200-"), true},
+		{"reversed status code range", StatusCodePattern(), types.StringValue("299-200"), true},
+		{"empty status code", StatusCodePattern(), types.StringValue("200,"), true},
+		{"invalid status code after a match", StatusCodePattern(), types.StringValue("0-599,oops"), true},
+		{"exit codes", ExitCodePattern(), types.StringValue("0,2-3"), false},
+		{"invalid exit codes", ExitCodePattern(), types.StringValue("0-a"), true},
+		{"invalid exit code after a match", ExitCodePattern(), types.StringValue("0,abc"), true},
+		{"invalid exit code range after a match", ExitCodePattern(), types.StringValue("0,2-x"), true},
+		{"base64", Base64(), types.StringValue("aGVsbG8="), false},
+		{"invalid base64", Base64(), types.StringValue("aGVsbG8"), true},
+		{"null value", Regex(), types.StringNull(), false},
+		{"unknown value", StatusCodePattern(), types.StringUnknown(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validator.StringRequest{Path: path.Root("test"), ConfigValue: tt.value}
+			resp := &validator.StringResponse{}
+			tt.validator.ValidateString(context.Background(), req, resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantErr {
+				t.Errorf("ValidateString(%s) got errors %v, wantErr %v", tt.value, resp.Diagnostics, tt.wantErr)
+			}
+		})
+	}
+}